@@ -1,12 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/jmwri/neatgo/neat"
 	"github.com/jmwri/neatgo/network"
 	"math"
 	"math/rand"
-	"sync"
 	"time"
 )
 
@@ -43,40 +43,26 @@ func main() {
 	cfg.MateCrossoverRate = .6
 	cfg.MateBestRate = .5
 
-	cfg.TopGenomesFromSpeciesToFill = 4
+	cfg.Reporters = []neat.Reporter{neat.NewStdoutReporter()}
 
 	pop, err := neat.GeneratePopulation(cfg)
 	if err != nil {
 		panic(err)
 	}
 
-	solved := false
-	var generation int
-	for generation = 1; generation <= 500; generation++ {
-		pop = playGame(pop)
-		bestFitness := pop.BestGenomeFitness
-		bestNumNodes := pop.BestGenome.NumNodes()
-		bestNumConnections := pop.BestGenome.NumConnections()
-		popSize := len(pop.Genomes)
-		numSpecies := len(pop.Species)
-
-		fmt.Printf(`Generation %d
-BestFitness: %f
-BestNodes: %d
-BestConnections: %d
-PopSize: %d
-NumSpecies: %d
--------------------------
-`, generation, bestFitness, bestNumNodes, bestNumConnections, popSize, numSpecies)
-		if bestFitness >= 3.9 {
-			solved = true
-			break
-		}
+	pop, summary, err := neat.Run(context.Background(), pop, playGame, neat.RunOptions{
+		StopConditions: []neat.StopCondition{
+			neat.FitnessReached(3.9),
+			neat.MaxGenerations(500),
+		},
+	})
+	if err != nil {
+		panic(err)
 	}
-	if solved {
-		fmt.Printf("Solved xor after %d generations with fitness %f\n", generation, pop.BestEverGenomeFitness)
+	if summary.Reason == neat.StopFitnessReached {
+		fmt.Printf("Solved xor after %d generations with fitness %f\n", summary.Generations, summary.BestFitness)
 	} else {
-		fmt.Printf("Failed xor after %d generations with fitness %f\n", generation, pop.BestEverGenomeFitness)
+		fmt.Printf("Failed xor after %d generations with fitness %f\n", summary.Generations, summary.BestFitness)
 	}
 
 	runTest(pop.BestEverGenome)
@@ -91,56 +77,44 @@ func outputToAnswer(output []float64) float64 {
 	}
 }
 
-func playGame(pop neat.Population) neat.Population {
-	clientStates := pop.States()
-	wg := sync.WaitGroup{}
-	wg.Add(len(clientStates))
+func playGame(state neat.ClientGenomeState) {
 	fitnessFunc := func(expectedOutput, output float64) float64 {
 		return 1 - math.Pow(output-expectedOutput, 2)
 	}
-	for _, state := range clientStates {
-		go func(state neat.ClientGenomeState) {
-			defer wg.Done()
-			inputs := [][]float64{
-				{0, 0},
-				{1, 0},
-				{0, 1},
-				{1, 1},
-			}
-			answer := [][]float64{
-				{0},
-				{1},
-				{1},
-				{0},
-			}
-
-			availableIndices := []int{0, 1, 2, 3}
-			rand.Shuffle(len(availableIndices), func(i, j int) {
-				availableIndices[i], availableIndices[j] = availableIndices[j], availableIndices[i]
-			})
-
-			fitness := .0
-			for _, i := range availableIndices {
-				input := inputs[i]
-				answer := answer[i][0]
-				state.SendInput() <- input
-				select {
-				case output := <-state.GetOutput():
-					fitness += fitnessFunc(answer, output[0])
-				case err := <-state.GetError():
-					fmt.Printf("failed to process: %s\n", err)
-				}
-			}
-			close(state.SendInput())
-
-			state.SendFitness() <- fitness
-			close(state.SendFitness())
-		}(state)
+	inputs := [][]float64{
+		{0, 0},
+		{1, 0},
+		{0, 1},
+		{1, 1},
+	}
+	answer := [][]float64{
+		{0},
+		{1},
+		{1},
+		{0},
+	}
+
+	availableIndices := []int{0, 1, 2, 3}
+	rand.Shuffle(len(availableIndices), func(i, j int) {
+		availableIndices[i], availableIndices[j] = availableIndices[j], availableIndices[i]
+	})
+
+	fitness := .0
+	for _, i := range availableIndices {
+		input := inputs[i]
+		answer := answer[i][0]
+		state.SendInput() <- input
+		select {
+		case output := <-state.GetOutput():
+			fitness += fitnessFunc(answer, output[0])
+		case err := <-state.GetError():
+			fmt.Printf("failed to process: %s\n", err)
+		}
 	}
-	pop = neat.RunGeneration(pop)
-	wg.Wait()
+	close(state.SendInput())
 
-	return pop
+	state.SendFitness() <- fitness
+	close(state.SendFitness())
 }
 
 func runTest(genome neat.Genome) {
@@ -0,0 +1,103 @@
+package neat
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+)
+
+// checkpoint contains the parts of a Population needed to continue evolving it.
+// Config isn't saved, because it contains functions and providers.
+type checkpoint struct {
+	Generation             int
+	Genomes                []Genome
+	GenomeFitness          []float64
+	Species                []Species
+	BestEverGenome         Genome
+	BestEverGenomeFitness  float64
+	BestGenome             Genome
+	BestGenomeFitness      float64
+	SpeciesCompatThreshold float64
+	LastSpeciesID          int
+	SpeciesHistory         map[int][]SpeciesRecord
+	NoveltyArchive         [][]float64
+	HallOfFame             []HallOfFameEntry
+	FitnessHistory         map[uint64][]float64
+}
+
+// SaveCheckpoint writes the population so that it can be restored with LoadCheckpoint.
+// Checkpoints are gob encoded, because fitness may be infinite before the first generation is evaluated.
+func SaveCheckpoint(w io.Writer, pop Population) error {
+	err := gob.NewEncoder(w).Encode(checkpoint{
+		Generation:             pop.Generation,
+		Genomes:                pop.Genomes,
+		GenomeFitness:          pop.GenomeFitness,
+		Species:                pop.Species,
+		BestEverGenome:         pop.BestEverGenome,
+		BestEverGenomeFitness:  pop.BestEverGenomeFitness,
+		BestGenome:             pop.BestGenome,
+		BestGenomeFitness:      pop.BestGenomeFitness,
+		SpeciesCompatThreshold: pop.SpeciesCompatThreshold,
+		LastSpeciesID:          pop.LastSpeciesID,
+		SpeciesHistory:         pop.SpeciesHistory,
+		NoveltyArchive:         pop.NoveltyArchive,
+		HallOfFame:             pop.HallOfFame,
+		FitnessHistory:         pop.FitnessHistory,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// LoadCheckpoint reads a population written by SaveCheckpoint, ready for the next call to RunGeneration.
+// Every genome is validated against cfg, and cfg.IDProvider is advanced past every innovation ID in the checkpoint.
+func LoadCheckpoint(r io.Reader, cfg Config) (Population, error) {
+	c := checkpoint{}
+	if err := gob.NewDecoder(r).Decode(&c); err != nil {
+		return Population{}, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	if len(c.GenomeFitness) != len(c.Genomes) {
+		return Population{}, fmt.Errorf("checkpoint has %d genomes but %d fitness values", len(c.Genomes), len(c.GenomeFitness))
+	}
+
+	genomes := append([]Genome{}, c.Genomes...)
+	for _, entry := range c.HallOfFame {
+		genomes = append(genomes, entry.Genome)
+	}
+	for i, genome := range genomes {
+		if problems := ValidateGenome(cfg, genome); len(problems) > 0 {
+			return Population{}, fmt.Errorf("checkpoint genome %d is invalid: %w", i, problems[0])
+		}
+	}
+	advanceIDProvider(cfg.IDProvider, append(genomes, c.BestEverGenome, c.BestGenome)...)
+
+	pop := Population{
+		Cfg:                    cfg,
+		Genomes:                c.Genomes,
+		GenomeFitness:          c.GenomeFitness,
+		Species:                c.Species,
+		Generation:             c.Generation,
+		BestEverGenome:         c.BestEverGenome,
+		BestEverGenomeFitness:  c.BestEverGenomeFitness,
+		BestGenome:             c.BestGenome,
+		BestGenomeFitness:      c.BestGenomeFitness,
+		SpeciesCompatThreshold: c.SpeciesCompatThreshold,
+		DistanceCache:          NewDistanceCache(),
+		LastSpeciesID:          c.LastSpeciesID,
+		SpeciesHistory:         c.SpeciesHistory,
+		NoveltyArchive:         c.NoveltyArchive,
+		HallOfFame:             c.HallOfFame,
+		FitnessHistory:         c.FitnessHistory,
+	}
+	if pop.Species == nil {
+		pop.Species = make([]Species, 0)
+	}
+	if pop.SpeciesHistory == nil {
+		pop.SpeciesHistory = make(map[int][]SpeciesRecord)
+	}
+	if pop.FitnessHistory == nil {
+		pop.FitnessHistory = make(map[uint64][]float64)
+	}
+	return buildGenomeStates(pop), nil
+}
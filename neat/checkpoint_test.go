@@ -0,0 +1,71 @@
+package neat_test
+
+import (
+	"bytes"
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLoadCheckpoint(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 20
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		pop, err = runGenerationWithFitness(pop, func(i int) float64 {
+			return float64(i)
+		})
+		assert.NoError(t, err)
+	}
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, neat.SaveCheckpoint(&buf, pop))
+
+	loadCfg := neat.DefaultConfig(2, 1)
+	loadCfg.PopulationSize = 20
+	loaded, err := neat.LoadCheckpoint(&buf, loadCfg)
+	assert.NoError(t, err)
+	assert.Equal(t, pop.Generation, loaded.Generation)
+	assert.Equal(t, pop.Genomes, loaded.Genomes)
+	assert.Equal(t, pop.Species, loaded.Species)
+	assert.Equal(t, pop.HallOfFame, loaded.HallOfFame)
+	assert.Equal(t, pop.SpeciesHistory, loaded.SpeciesHistory)
+	assert.Equal(t, pop.BestEverGenomeFitness, loaded.BestEverGenomeFitness)
+	assert.Len(t, loaded.States(), len(loaded.Genomes))
+
+	// The loaded population carries on evolving.
+	loaded, err = runGenerationWithFitness(loaded, func(i int) float64 {
+		return float64(i)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, pop.Generation+1, loaded.Generation)
+	assert.Len(t, loaded.Genomes, loadCfg.PopulationSize)
+}
+
+func TestLoadCheckpoint_Unevaluated(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 5
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, neat.SaveCheckpoint(&buf, pop))
+	loaded, err := neat.LoadCheckpoint(&buf, neat.DefaultConfig(2, 1))
+	assert.NoError(t, err)
+	assert.Equal(t, pop.Genomes, loaded.Genomes)
+	assert.Equal(t, pop.BestEverGenomeFitness, loaded.BestEverGenomeFitness)
+}
+
+func TestLoadCheckpoint_InvalidGenome(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 5
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, neat.SaveCheckpoint(&buf, pop))
+	// The checkpoint genomes have 2 inputs.
+	_, err = neat.LoadCheckpoint(&buf, neat.DefaultConfig(3, 1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,213 @@
+package neat
+
+import (
+	"fmt"
+	"github.com/jmwri/neatgo/util"
+	"math/rand"
+	"sync"
+)
+
+// MatchFunc plays a game between a and b, and returns the score of a between 0 for a loss and 1 for a win.
+// The score of b is 1 minus the score of a. Matches are played concurrently.
+type MatchFunc func(a, b Genome) float64
+
+type CoevolutionConfig struct {
+	SampleSize           int // How many opponents to choose from the other population by shared sampling.
+	HallOfFameSampleSize int // How many extra opponents to choose from the other population's hall of fame.
+	HallOfFameSize       int // How many past generation champions to keep in each hall of fame. 0 is unlimited.
+}
+
+func DefaultCoevolutionConfig() CoevolutionConfig {
+	return CoevolutionConfig{
+		SampleSize:           10,
+		HallOfFameSampleSize: 5,
+		HallOfFameSize:       0,
+	}
+}
+
+// Coevolution evolves a population of hosts against a population of parasites. Each genome is scored by the matches
+// it plays against a sample of opponents from the other population, and from the other population's hall of fame.
+type Coevolution struct {
+	Cfg        CoevolutionConfig
+	Hosts      Population
+	Parasites  Population
+	Generation int
+	// HostOpponents are the parasites that hosts will play in the next generation.
+	HostOpponents []Genome
+	// ParasiteOpponents are the hosts that parasites will play in the next generation.
+	ParasiteOpponents []Genome
+	// HostHallOfFame contains the best host of each generation.
+	HostHallOfFame []Genome
+	// ParasiteHallOfFame contains the best parasite of each generation.
+	ParasiteHallOfFame []Genome
+}
+
+// GenerateCoevolution generates both populations. The parasites use the IDProvider of the hosts.
+func GenerateCoevolution(cfg CoevolutionConfig, hostCfg Config, parasiteCfg Config) (Coevolution, error) {
+	parasiteCfg.IDProvider = hostCfg.IDProvider
+	hosts, err := GeneratePopulation(hostCfg)
+	if err != nil {
+		return Coevolution{}, fmt.Errorf("failed to generate hosts: %w", err)
+	}
+	parasites, err := GeneratePopulation(parasiteCfg)
+	if err != nil {
+		return Coevolution{}, fmt.Errorf("failed to generate parasites: %w", err)
+	}
+	return Coevolution{
+		Cfg:       cfg,
+		Hosts:     hosts,
+		Parasites: parasites,
+	}, nil
+}
+
+// RunCoevolutionGeneration plays every host and parasite against their opponents with match, where hosts are always a
+// and parasites are always b. Fitness is calculated with competitive fitness sharing, so beating an opponent that few
+// others beat is worth more. The opponents for the next generation are then chosen by shared sampling, the champions
+// are added to the halls of fame, and both populations are bred with NextGeneration.
+func RunCoevolutionGeneration(c Coevolution, match MatchFunc) (Coevolution, error) {
+	c.Generation++
+	for _, pop := range []Population{c.Hosts, c.Parasites} {
+		pop := pop
+		report(pop, func(r Reporter) {
+			r.GenerationStart(pop)
+		})
+	}
+	hostOpponents := coevolutionOpponents(c.Cfg, c.HostOpponents, c.ParasiteHallOfFame, c.Parasites)
+	parasiteOpponents := coevolutionOpponents(c.Cfg, c.ParasiteOpponents, c.HostHallOfFame, c.Hosts)
+
+	hostScores := playMatches(c.Hosts.Genomes, hostOpponents, match)
+	parasiteScores := playMatches(c.Parasites.Genomes, parasiteOpponents, func(parasite, host Genome) float64 {
+		return 1 - match(host, parasite)
+	})
+	c.Hosts.GenomeFitness = CompetitiveFitnessSharing(hostScores)
+	c.Parasites.GenomeFitness = CompetitiveFitnessSharing(parasiteScores)
+
+	c.HostOpponents = sampleGenomes(c.Parasites.Genomes, SharedSampling(parasiteScores, c.Cfg.SampleSize))
+	c.ParasiteOpponents = sampleGenomes(c.Hosts.Genomes, SharedSampling(hostScores, c.Cfg.SampleSize))
+	c.HostHallOfFame = addToCoevolutionHallOfFame(c.Cfg, c.HostHallOfFame, c.Hosts)
+	c.ParasiteHallOfFame = addToCoevolutionHallOfFame(c.Cfg, c.ParasiteHallOfFame, c.Parasites)
+
+	var err error
+	c.Hosts, err = NextGeneration(c.Hosts)
+	if err != nil {
+		return c, fmt.Errorf("hosts: %w", err)
+	}
+	c.Parasites, err = NextGeneration(c.Parasites)
+	if err != nil {
+		return c, fmt.Errorf("parasites: %w", err)
+	}
+	return c, nil
+}
+
+// CompetitiveFitnessSharing calculates fitness from scores, where scores[i][j] is the score of genome i against
+// opponent j. Each opponent is worth 1 in total, shared between genomes by their score against it.
+func CompetitiveFitnessSharing(scores [][]float64) []float64 {
+	opponentTotals := make(map[int]float64)
+	for _, genomeScores := range scores {
+		for j, score := range genomeScores {
+			opponentTotals[j] += score
+		}
+	}
+	fitness := make([]float64, len(scores))
+	for i, genomeScores := range scores {
+		for j, score := range genomeScores {
+			if opponentTotals[j] > 0 {
+				fitness[i] += score / opponentTotals[j]
+			}
+		}
+	}
+	return fitness
+}
+
+// SharedSampling chooses up to n genomes to use as opponents, where scores[i][j] is the score of genome i against
+// opponent j. Genomes are chosen one at a time, preferring those that do well against opponents that the genomes
+// already chosen do badly against, so that the sample is difficult in as many ways as possible.
+func SharedSampling(scores [][]float64, n int) []int {
+	chosen := make([]int, 0, n)
+	isChosen := make(map[int]bool)
+	// beaten contains how much each opponent has been beaten by the chosen genomes.
+	beaten := make(map[int]float64)
+	for len(chosen) < n && len(chosen) < len(scores) {
+		best := -1
+		bestSample := 0.0
+		for i, genomeScores := range scores {
+			if isChosen[i] {
+				continue
+			}
+			sample := 0.0
+			for j, score := range genomeScores {
+				sample += score / (1 + beaten[j])
+			}
+			if best == -1 || sample > bestSample {
+				best = i
+				bestSample = sample
+			}
+		}
+		chosen = append(chosen, best)
+		isChosen[best] = true
+		for j, score := range scores[best] {
+			beaten[j] += score
+		}
+	}
+	return chosen
+}
+
+// coevolutionOpponents returns the opponents to play this generation. Random opponents are chosen from the other
+// population when there is no sample from the previous generation.
+func coevolutionOpponents(cfg CoevolutionConfig, sample []Genome, hallOfFame []Genome, other Population) []Genome {
+	opponents := append([]Genome{}, sample...)
+	if len(opponents) == 0 {
+		for _, i := range rand.Perm(len(other.Genomes)) {
+			if len(opponents) >= cfg.SampleSize {
+				break
+			}
+			opponents = append(opponents, other.Genomes[i])
+		}
+	}
+	if len(hallOfFame) > 0 {
+		for i := 0; i < cfg.HallOfFameSampleSize; i++ {
+			opponents = append(opponents, hallOfFame[util.IntBetween(0, len(hallOfFame)-1)])
+		}
+	}
+	return opponents
+}
+
+// playMatches plays every genome against every opponent, and returns the score of each genome against each opponent.
+func playMatches(genomes []Genome, opponents []Genome, match MatchFunc) [][]float64 {
+	scores := make([][]float64, len(genomes))
+	wg := sync.WaitGroup{}
+	wg.Add(len(genomes))
+	for i := range genomes {
+		go func(i int) {
+			defer wg.Done()
+			scores[i] = make([]float64, len(opponents))
+			for j, opponent := range opponents {
+				scores[i][j] = match(genomes[i], opponent)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return scores
+}
+
+func sampleGenomes(genomes []Genome, indexes []int) []Genome {
+	sample := make([]Genome, len(indexes))
+	for i, genomeIndex := range indexes {
+		sample[i] = genomes[genomeIndex]
+	}
+	return sample
+}
+
+// addToCoevolutionHallOfFame adds the fittest genome of the population to the hall of fame, removing the oldest if
+// it is full.
+func addToCoevolutionHallOfFame(cfg CoevolutionConfig, hallOfFame []Genome, pop Population) []Genome {
+	if len(pop.Genomes) == 0 {
+		return hallOfFame
+	}
+	best := sortByFitness(pop, genomeIndexes(pop))[0]
+	hallOfFame = append(hallOfFame, pop.Genomes[best])
+	if cfg.HallOfFameSize > 0 && len(hallOfFame) > cfg.HallOfFameSize {
+		hallOfFame = hallOfFame[len(hallOfFame)-cfg.HallOfFameSize:]
+	}
+	return hallOfFame
+}
@@ -0,0 +1,82 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/jmwri/neatgo/network"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCompetitiveFitnessSharing(t *testing.T) {
+	scores := [][]float64{
+		// Only genome 0 beats opponent 0, and everyone beats opponent 2.
+		{1, 0, 1},
+		{0, 1, 1},
+		{0, 1, 1},
+		{0, 0, 0},
+	}
+	fitness := neat.CompetitiveFitnessSharing(scores)
+	assert.InDeltaSlice(t, []float64{1 + 1./3, .5 + 1./3, .5 + 1./3, 0}, fitness, 1e-9)
+}
+
+func TestSharedSampling(t *testing.T) {
+	scores := [][]float64{
+		{1, 0, 0, 0},
+		{1, 1, 1, 0},
+		{0, 0, 0, 1},
+		{1, 0, 0, 0},
+	}
+	// Genome 1 beats the most, then genome 2 is the only one to beat opponent 3.
+	assert.Equal(t, []int{1, 2}, neat.SharedSampling(scores, 2))
+	assert.Len(t, neat.SharedSampling(scores, 10), 4)
+}
+
+func TestRunCoevolutionGeneration(t *testing.T) {
+	hostCfg := neat.DefaultConfig(1, 1)
+	hostCfg.PopulationSize = 10
+	parasiteCfg := neat.DefaultConfig(1, 1)
+	parasiteCfg.PopulationSize = 8
+	c, err := neat.GenerateCoevolution(neat.CoevolutionConfig{
+		SampleSize:           4,
+		HallOfFameSampleSize: 2,
+		HallOfFameSize:       3,
+	}, hostCfg, parasiteCfg)
+	assert.NoError(t, err)
+	assert.Same(t, c.Hosts.Cfg.IDProvider, c.Parasites.Cfg.IDProvider)
+
+	var matches int32
+	for i := 0; i < 5; i++ {
+		c, err = neat.RunCoevolutionGeneration(c, func(host, parasite neat.Genome) float64 {
+			atomic.AddInt32(&matches, 1)
+			return higherOutputWins(host, parasite)
+		})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 5, c.Generation)
+	assert.Equal(t, 5, c.Hosts.Generation)
+	assert.Equal(t, 5, c.Parasites.Generation)
+	assert.Len(t, c.HostOpponents, 4)
+	assert.Len(t, c.ParasiteOpponents, 4)
+	assert.Len(t, c.HostHallOfFame, 3)
+	assert.Len(t, c.ParasiteHallOfFame, 3)
+	assert.Len(t, c.Hosts.Genomes, 10)
+	assert.Len(t, c.Parasites.Genomes, 8)
+	// Every generation, each host plays 4 sampled parasites and 2 from the hall of fame, apart from the first.
+	// Parasites do the same.
+	assert.Equal(t, int32(10*4+8*4+4*(10*6+8*6)), matches)
+}
+
+// higherOutputWins is a game where the genome with the highest output for an input of 1 wins.
+func higherOutputWins(a, b neat.Genome) float64 {
+	aOutput, _ := network.Activate(a.Layers.Nodes(), a.Connections, []float64{1})
+	bOutput, _ := network.Activate(b.Layers.Nodes(), b.Connections, []float64{1})
+	switch {
+	case aOutput[0] > bOutput[0]:
+		return 1
+	case aOutput[0] < bOutput[0]:
+		return 0
+	default:
+		return .5
+	}
+}
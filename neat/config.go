@@ -37,21 +37,50 @@ type Config struct {
 	WeightMutationPower          float64 // How much to mutate the weight. Calculated as connection.weight +/- (connection.weight*power).
 	WeightReplaceRate            float64 // How often to create a completely new weight, instead of mutating the existing one.
 	// Speciation
-	SpeciesElitism               int     // The number of top species to protect from stagnation.
-	SpeciesCompatExcessCoeff     float64 // How important are disjoint + excess genes when calculating species?
-	SpeciesCompatBiasDiffCoeff   float64 // How important are node biases when calculating species?
-	SpeciesCompatWeightDiffCoeff float64 // How important are connection weights when calculating species?
-	SpeciesCompatThreshold       float64 // How similar should genomes be to be considered the same species? Lower = more similar.
-	SpeciesStalenessThreshold    int     // If species does not improve after this many generations it will be removed.
+	DistanceFunc                 DistanceFunc // How to measure the distance between a genome and a species representative.
+	SpeciesElitism               int          // The number of top species to protect from stagnation.
+	SpeciesCompatExcessCoeff     float64      // How important are disjoint + excess genes when calculating species?
+	SpeciesCompatBiasDiffCoeff   float64      // How important are node biases when calculating species?
+	SpeciesCompatWeightDiffCoeff float64      // How important are connection weights when calculating species?
+	SpeciesCompatThreshold       float64      // How similar should genomes be to be considered the same species? Lower = more similar.
+	SpeciesStalenessThreshold    int          // If species does not improve after this many generations it will be removed.
+	TargetSpeciesCount           int          // If set, the compatibility threshold is adjusted each generation to aim for this many species.
+	SpeciesCompatThresholdStep   float64      // How much to adjust the compatibility threshold by each generation when targeting a species count.
+	MinSpeciesCompatThreshold    float64      // The lowest the compatibility threshold can be adjusted to.
+	MaxSpeciesCompatThreshold    float64      // The highest the compatibility threshold can be adjusted to.
+	SpeciesYoungAgeThreshold     int          // Species younger than this many generations have their fitness boosted. 0 disables the boost.
+	SpeciesYoungFitnessBoost     float64      // How much to multiply the fitness of young species by.
+	SpeciesOldAgeThreshold       int          // Species older than this many generations have their fitness penalised. 0 disables the penalty.
+	SpeciesOldFitnessPenalty     float64      // How much to multiply the fitness of old species by.
+	// Hooks
+	Reporters    []Reporter           // Notified of events during each generation.
+	OnExtinction func(pop Population) // Deprecated: add ExtinctionFunc(fn) to Reporters instead. It is reported to in the same way.
+	// Fitness
+	FitnessShaping       FitnessTransform  // How to reshape fitness after evaluation and before speciation, so offspring allocation doesn't depend on the scale of fitness. nil leaves fitness unchanged.
+	FitnessNormalisation FitnessTransform  // How to make fitness non-negative before fitness sharing and offspring allocation.
+	MultiObjective       bool              // Rank genomes by Pareto dominance of their objectives, instead of using their fitness. The best genome and hall of fame still use the fitness sent with each genome.
+	EvaluationRepeats    int               // How many times to evaluate each genome every generation, for noisy tasks.
+	FitnessAggregator    FitnessAggregator // How to combine the repeated evaluations of a genome into its fitness.
+	FitnessHistoryLength int               // How many of a genome's most recent evaluations to combine, including previous generations. 0 only uses this generation.
+	// Novelty search
+	NoveltySearch                bool    // Score genomes by how novel their behaviour is, instead of only by their fitness.
+	NoveltyNeighbours            int     // How many nearest behaviours to average the distance to when calculating novelty.
+	NoveltyArchiveThreshold      float64 // Genomes with at least this novelty have their behaviour added to the archive.
+	NoveltyArchiveAddProbability float64 // How often to add a genome's behaviour to the archive regardless of its novelty.
+	NoveltyArchiveMaxSize        int     // The most behaviours to keep in the archive, removing the oldest first. 0 is unlimited.
+	NoveltyFitnessWeight         float64 // How much fitness counts compared to novelty. 0 = pure novelty, 1 = pure fitness.
 	// Crossover
-	SurvivalThreshold float64 // The fraction of each species to allow for reproduction.
-	MateCrossoverRate float64 // How often to perform crossover between 2 parents in same species. Otherwise, take a random genome in the species.
-	MateBestRate      float64 // How often should we take the gene from the best genome.
+	ParentSelector    Selector // How to choose parents from each species for reproduction.
+	SurvivalThreshold float64  // The fraction of each species to allow for reproduction.
+	MateCrossoverRate float64  // How often to perform crossover between 2 parents in same species. Otherwise, take a random genome in the species.
+	MateBestRate      float64  // How often should we take the gene from the best genome.
 	// Population
-	ResetOnExtinction           bool // TODO: If all species are extinct due to stagnation, should a random population be generated?
+	ResetOnExtinction           bool // If all species are extinct due to stagnation, should a new population be generated? Otherwise RunGeneration returns ErrPopulationExtinct.
+	ResetFromBestEverGenome     bool // When resetting after extinction, fill the population with mutated copies of the best ever genome instead of random genomes.
 	Elitism                     int  // How many top genomes to take from each species to take without mutation.
-	TopGenomesFromSpeciesToFill int  // How many top genomes to take from each species to fill any remaining population.
+	TopGenomesFromSpeciesToFill int  // Deprecated: offspring are allocated to species to fill the whole population, so this has no effect.
 	MinSpeciesSize              int  // Minimum species size
+	HallOfFameSize              int  // How many of the best distinct genomes ever seen to keep in the hall of fame. 0 disables it.
 }
 
 func DefaultConfig(layers ...int) Config {
@@ -86,20 +115,46 @@ func DefaultConfig(layers ...int) Config {
 		WeightMutationPower:          .2,
 		WeightReplaceRate:            .01,
 
+		DistanceFunc:                 NeatgoDistance,
 		SpeciesElitism:               2,
 		SpeciesCompatExcessCoeff:     1,
 		SpeciesCompatBiasDiffCoeff:   .5,
 		SpeciesCompatWeightDiffCoeff: .5,
 		SpeciesCompatThreshold:       5,
 		SpeciesStalenessThreshold:    15,
+		TargetSpeciesCount:           0,
+		SpeciesCompatThresholdStep:   .3,
+		MinSpeciesCompatThreshold:    .3,
+		MaxSpeciesCompatThreshold:    100,
+		SpeciesYoungAgeThreshold:     0,
+		SpeciesYoungFitnessBoost:     1.2,
+		SpeciesOldAgeThreshold:       0,
+		SpeciesOldFitnessPenalty:     .5,
 
+		FitnessShaping:       nil,
+		FitnessNormalisation: MinShiftFitness,
+		MultiObjective:       false,
+		EvaluationRepeats:    1,
+		FitnessAggregator:    MeanFitness,
+		FitnessHistoryLength: 0,
+
+		NoveltySearch:                false,
+		NoveltyNeighbours:            15,
+		NoveltyArchiveThreshold:      1,
+		NoveltyArchiveAddProbability: 0,
+		NoveltyArchiveMaxSize:        0,
+		NoveltyFitnessWeight:         0,
+
+		ParentSelector:    RouletteSelector{},
 		SurvivalThreshold: .3,
 		MateCrossoverRate: .5,
 		MateBestRate:      .8,
 
 		ResetOnExtinction:           false,
+		ResetFromBestEverGenome:     false,
 		Elitism:                     2,
 		TopGenomesFromSpeciesToFill: 2,
 		MinSpeciesSize:              1,
+		HallOfFameSize:              10,
 	}
 }
@@ -0,0 +1,129 @@
+package neat
+
+import "math"
+
+// StageCriterion decides whether a curriculum stage is complete, from the best fitness of each generation evaluated in
+// the stage so far.
+type StageCriterion func(stageFitness []float64) bool
+
+// FitnessThreshold completes a stage once the champion of a generation reaches threshold.
+func FitnessThreshold(threshold float64) StageCriterion {
+	return func(stageFitness []float64) bool {
+		return len(stageFitness) > 0 && stageFitness[len(stageFitness)-1] >= threshold
+	}
+}
+
+// FitnessPlateau completes a stage once the best fitness hasn't improved by at least minImprovement for generations.
+func FitnessPlateau(generations int, minImprovement float64) StageCriterion {
+	return func(stageFitness []float64) bool {
+		if len(stageFitness) <= generations {
+			return false
+		}
+		split := len(stageFitness) - generations
+		before := math.Inf(-1)
+		for _, fitness := range stageFitness[:split] {
+			before = math.Max(before, fitness)
+		}
+		after := math.Inf(-1)
+		for _, fitness := range stageFitness[split:] {
+			after = math.Max(after, fitness)
+		}
+		return after-before < minImprovement
+	}
+}
+
+// StageGenerations completes a stage after it has been evaluated for generations.
+func StageGenerations(generations int) StageCriterion {
+	return func(stageFitness []float64) bool {
+		return len(stageFitness) >= generations
+	}
+}
+
+// AnyCriterion completes a stage as soon as any of the criteria are met.
+func AnyCriterion(criteria ...StageCriterion) StageCriterion {
+	return func(stageFitness []float64) bool {
+		for _, criterion := range criteria {
+			if criterion(stageFitness) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// CurriculumStage is one step of a curriculum. Task contains the settings that evaluators use for the stage.
+type CurriculumStage[T any] struct {
+	Name string
+	Task T
+	// Complete decides when to move on to the next stage. The last stage is never left.
+	Complete StageCriterion
+}
+
+// StageTransition records a curriculum moving from one stage to the next.
+type StageTransition struct {
+	From       int
+	To         int
+	Generation int
+	// BestFitness is the fitness of the champion of the generation that completed the stage.
+	BestFitness float64
+	Champion    Genome
+}
+
+// Curriculum evolves a population through a series of stages, usually increasing in difficulty.
+type Curriculum[T any] struct {
+	Stages     []CurriculumStage[T]
+	Population Population
+	// Stage is the index of the current stage.
+	Stage int
+	// StageFitness contains the best fitness of each generation evaluated in the current stage.
+	StageFitness []float64
+	Transitions  []StageTransition
+}
+
+func NewCurriculum[T any](pop Population, stages ...CurriculumStage[T]) Curriculum[T] {
+	return Curriculum[T]{
+		Stages:       stages,
+		Population:   pop,
+		StageFitness: make([]float64, 0),
+		Transitions:  make([]StageTransition, 0),
+	}
+}
+
+// CurrentStage returns the stage that the next generation will be evaluated on.
+func (c Curriculum[T]) CurrentStage() CurriculumStage[T] {
+	return c.Stages[c.Stage]
+}
+
+// RunCurriculumGeneration evaluates a generation with the task of the current stage, and moves on to the next stage if
+// the current one is complete. When the stage changes, species staleness is reset and the fitness history used for
+// repeated evaluation is cleared, because fitness on the old task doesn't compare with fitness on the new one.
+func RunCurriculumGeneration[T any](c Curriculum[T], evaluator func(task T, state ClientGenomeState)) (Curriculum[T], error) {
+	stage := c.CurrentStage()
+	pop, err := EvaluateGeneration(c.Population, func(state ClientGenomeState) {
+		evaluator(stage.Task, state)
+	})
+	c.Population = pop
+	if err != nil {
+		return c, err
+	}
+	c.StageFitness = append(c.StageFitness, pop.BestGenomeFitness)
+
+	if c.Stage == len(c.Stages)-1 || stage.Complete == nil || !stage.Complete(c.StageFitness) {
+		return c, nil
+	}
+	c.Transitions = append(c.Transitions, StageTransition{
+		From:        c.Stage,
+		To:          c.Stage + 1,
+		Generation:  pop.Generation,
+		BestFitness: pop.BestGenomeFitness,
+		Champion:    pop.BestGenome,
+	})
+	c.Stage++
+	c.StageFitness = make([]float64, 0)
+	for i := range c.Population.Species {
+		c.Population.Species[i].Staleness = 0
+		c.Population.Species[i].BestFitness = math.Inf(-1)
+	}
+	c.Population.FitnessHistory = make(map[uint64][]float64)
+	return c, nil
+}
@@ -0,0 +1,97 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestStageCriteria(t *testing.T) {
+	threshold := neat.FitnessThreshold(5)
+	assert.False(t, threshold(nil))
+	assert.False(t, threshold([]float64{6, 4}))
+	assert.True(t, threshold([]float64{4, 5}))
+
+	plateau := neat.FitnessPlateau(2, 1)
+	assert.False(t, plateau([]float64{1, 1}))
+	assert.True(t, plateau([]float64{1, 1.5, 1.9}))
+	assert.False(t, plateau([]float64{1, 1.5, 2}))
+	assert.True(t, plateau([]float64{1, 3, 2, 2.5}))
+
+	generations := neat.StageGenerations(3)
+	assert.False(t, generations([]float64{1, 2}))
+	assert.True(t, generations([]float64{1, 2, 3}))
+
+	either := neat.AnyCriterion(threshold, generations)
+	assert.True(t, either([]float64{5}))
+	assert.True(t, either([]float64{1, 1, 1}))
+	assert.False(t, either([]float64{1}))
+}
+
+func TestRunCurriculumGeneration(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 10
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	type task struct {
+		fitness float64
+	}
+	curriculum := neat.NewCurriculum(pop,
+		neat.CurriculumStage[task]{Name: "easy", Task: task{fitness: 10}, Complete: neat.FitnessThreshold(10)},
+		neat.CurriculumStage[task]{Name: "medium", Task: task{fitness: 1}, Complete: neat.StageGenerations(2)},
+		neat.CurriculumStage[task]{Name: "hard", Task: task{fitness: .5}, Complete: neat.StageGenerations(1)},
+	)
+
+	stages := make([]string, 0)
+	for i := 0; i < 5; i++ {
+		stages = append(stages, curriculum.CurrentStage().Name)
+		curriculum, err = neat.RunCurriculumGeneration(curriculum, func(task task, state neat.ClientGenomeState) {
+			close(state.SendInput())
+			state.SendFitness() <- task.fitness
+		})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []string{"easy", "medium", "medium", "hard", "hard"}, stages)
+	assert.Equal(t, 2, curriculum.Stage)
+	assert.Equal(t, []float64{.5, .5}, curriculum.StageFitness)
+
+	assert.Len(t, curriculum.Transitions, 2)
+	assert.Equal(t, neat.StageTransition{
+		From:        0,
+		To:          1,
+		Generation:  1,
+		BestFitness: 10,
+		Champion:    curriculum.Transitions[0].Champion,
+	}, curriculum.Transitions[0])
+	assert.Equal(t, 3, curriculum.Transitions[1].Generation)
+	assert.Equal(t, 1., curriculum.Transitions[1].BestFitness)
+}
+
+func TestRunCurriculumGeneration_ResetsStaleness(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 10
+	cfg.SpeciesStalenessThreshold = 2
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	curriculum := neat.NewCurriculum(pop,
+		neat.CurriculumStage[float64]{Task: 10, Complete: neat.StageGenerations(2)},
+		neat.CurriculumStage[float64]{Task: 1},
+	)
+
+	for i := 0; i < 2; i++ {
+		curriculum, err = neat.RunCurriculumGeneration(curriculum, func(fitness float64, state neat.ClientGenomeState) {
+			close(state.SendInput())
+			state.SendFitness() <- fitness
+		})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, curriculum.Stage)
+	assert.NotEmpty(t, curriculum.Population.Species)
+	for _, species := range curriculum.Population.Species {
+		assert.Equal(t, 0, species.Staleness)
+		assert.Equal(t, math.Inf(-1), species.BestFitness)
+	}
+	assert.Empty(t, curriculum.Population.FitnessHistory)
+}
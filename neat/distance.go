@@ -0,0 +1,304 @@
+package neat
+
+import (
+	"github.com/jmwri/neatgo/network"
+	"math"
+	"sync"
+)
+
+// DistanceFunc measures how different two genomes are when calculating species. Lower means more similar.
+type DistanceFunc func(cfg Config, a, b Genome) float64
+
+// NeatgoDistance is the original neatgo compatibility formula.
+// Excess and disjoint genes are normalised by the size of a, ignoring the first 20 layers and nodes, and the average
+// weight and bias differences are 100 if the genomes share no genes.
+func NeatgoDistance(cfg Config, a, b Genome) float64 {
+	excessAndDisjoint := countExcessAndDisjointGenes(a, b)
+	averageWeightDiff := calculateAverageConnectionWeightDiff(a, b)
+	averageBiasDiff := calculateAverageNodeBiasDiff(a, b)
+
+	var largeGenomeNormaliser = (a.NumLayers() + a.NumNodes()) - 20
+	if largeGenomeNormaliser < 1 {
+		largeGenomeNormaliser = 1
+	}
+
+	excessAndDisjointDiff := cfg.SpeciesCompatExcessCoeff * float64(excessAndDisjoint) / float64(largeGenomeNormaliser)
+	weightDiff := cfg.SpeciesCompatWeightDiffCoeff * averageWeightDiff
+	biasDiff := cfg.SpeciesCompatBiasDiffCoeff * averageBiasDiff
+
+	return excessAndDisjointDiff + weightDiff + biasDiff
+}
+
+// NEATDistance is the compatibility distance from the original NEAT paper, δ = c1*E/N + c2*D/N + c3*W.
+// E and D are the excess and disjoint connection genes, both weighted by SpeciesCompatExcessCoeff, and N is the number
+// of connection genes in the larger genome, or 1 if both genomes have fewer than 20. W is the average weight difference
+// of matching connection genes, weighted by SpeciesCompatWeightDiffCoeff.
+func NEATDistance(cfg Config, a, b Genome) float64 {
+	aWeights := make(map[int]float64)
+	for _, connection := range a.Connections {
+		aWeights[connection.ID] = connection.Weight
+	}
+
+	matching := 0
+	totalWeightDiff := 0.0
+	for _, connection := range b.Connections {
+		if weight, ok := aWeights[connection.ID]; ok {
+			matching++
+			totalWeightDiff += math.Abs(weight - connection.Weight)
+		}
+	}
+	nonMatching := len(a.Connections) + len(b.Connections) - 2*matching
+
+	n := a.NumConnections()
+	if b.NumConnections() > n {
+		n = b.NumConnections()
+	}
+	if n < 20 {
+		n = 1
+	}
+
+	averageWeightDiff := 0.0
+	if matching > 0 {
+		averageWeightDiff = totalWeightDiff / float64(matching)
+	}
+
+	return cfg.SpeciesCompatExcessCoeff*float64(nonMatching)/float64(n) + cfg.SpeciesCompatWeightDiffCoeff*averageWeightDiff
+}
+
+// NeatPythonDistance is the genomic distance used by neat-python.
+// Node and connection distances are calculated separately and summed. Each is the weighted sum of the differences
+// between matching genes plus SpeciesCompatExcessCoeff for each disjoint gene, divided by the number of genes in the
+// larger genome. Matching nodes differ by their bias (weighted by SpeciesCompatBiasDiffCoeff) and 1 if their
+// activation functions differ, and matching connections by their weight (weighted by SpeciesCompatWeightDiffCoeff)
+// and 1 if only one is enabled.
+func NeatPythonDistance(cfg Config, a, b Genome) float64 {
+	aNodes := make(map[int]network.Node)
+	for _, node := range a.Layers.Nodes() {
+		aNodes[node.ID] = node
+	}
+	matchingNodes := 0
+	nodeDiff := 0.0
+	for _, node := range b.Layers.Nodes() {
+		aNode, ok := aNodes[node.ID]
+		if !ok {
+			continue
+		}
+		matchingNodes++
+		diff := math.Abs(aNode.Bias - node.Bias)
+		if aNode.ActivationFn != node.ActivationFn {
+			diff++
+		}
+		nodeDiff += diff
+	}
+
+	aConnections := make(map[int]network.Connection)
+	for _, connection := range a.Connections {
+		aConnections[connection.ID] = connection
+	}
+	matchingConnections := 0
+	connectionDiff := 0.0
+	for _, connection := range b.Connections {
+		aConnection, ok := aConnections[connection.ID]
+		if !ok {
+			continue
+		}
+		matchingConnections++
+		diff := math.Abs(aConnection.Weight - connection.Weight)
+		if aConnection.Enabled != connection.Enabled {
+			diff++
+		}
+		connectionDiff += diff
+	}
+
+	distance := 0.0
+	if maxNodes := math.Max(float64(a.NumNodes()), float64(b.NumNodes())); maxNodes > 0 {
+		disjointNodes := a.NumNodes() + b.NumNodes() - 2*matchingNodes
+		distance += (cfg.SpeciesCompatBiasDiffCoeff*nodeDiff + cfg.SpeciesCompatExcessCoeff*float64(disjointNodes)) / maxNodes
+	}
+	if maxConnections := math.Max(float64(a.NumConnections()), float64(b.NumConnections())); maxConnections > 0 {
+		disjointConnections := a.NumConnections() + b.NumConnections() - 2*matchingConnections
+		distance += (cfg.SpeciesCompatWeightDiffCoeff*connectionDiff + cfg.SpeciesCompatExcessCoeff*float64(disjointConnections)) / maxConnections
+	}
+	return distance
+}
+
+// BehaviouralDistance compares genomes by what they do rather than their genes.
+// Both networks are activated with each of the inputs, and the distance is the average euclidean distance between
+// their outputs. Genomes that can't be activated with the inputs are infinitely distant.
+func BehaviouralDistance(inputs [][]float64) DistanceFunc {
+	return func(cfg Config, a, b Genome) float64 {
+		if len(inputs) == 0 {
+			return 0
+		}
+		total := 0.0
+		for _, input := range inputs {
+			aOutput, err := network.Activate(a.Layers.Nodes(), a.Connections, input)
+			if err != nil {
+				return math.Inf(1)
+			}
+			bOutput, err := network.Activate(b.Layers.Nodes(), b.Connections, input)
+			if err != nil || len(aOutput) != len(bOutput) {
+				return math.Inf(1)
+			}
+			sumSquares := 0.0
+			for i := range aOutput {
+				sumSquares += math.Pow(aOutput[i]-bOutput[i], 2)
+			}
+			total += math.Sqrt(sumSquares)
+		}
+		return total / float64(len(inputs))
+	}
+}
+
+func NewDistanceCache() *DistanceCache {
+	return &DistanceCache{
+		mu:        sync.Mutex{},
+		distances: make(map[[2]uint64]float64),
+	}
+}
+
+// DistanceCache stores the distance between pairs of genomes, keyed by their fingerprints in the order they were
+// compared, so that repeated comparisons within a generation aren't recalculated.
+type DistanceCache struct {
+	mu        sync.Mutex
+	distances map[[2]uint64]float64
+}
+
+func (c *DistanceCache) Get(a, b uint64) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	distance, ok := c.distances[[2]uint64{a, b}]
+	return distance, ok
+}
+
+func (c *DistanceCache) Set(a, b uint64, distance float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.distances[[2]uint64{a, b}] = distance
+}
+
+func (c *DistanceCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.distances)
+}
+
+// Reset removes all cached distances.
+func (c *DistanceCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.distances = make(map[[2]uint64]float64)
+}
+
+// genomeDistance calculates the distance between genomes with the configured DistanceFunc, using the population's
+// DistanceCache if it has one.
+func genomeDistance(pop Population, a, b Genome) float64 {
+	if pop.DistanceCache == nil {
+		return fingerprintedDistance(pop, a, 0, b, 0)
+	}
+	return fingerprintedDistance(pop, a, a.Fingerprint(), b, b.Fingerprint())
+}
+
+// fingerprintedDistance is genomeDistance for genomes whose fingerprints are already known, so that callers comparing
+// the same genomes many times only hash them once. The fingerprints are ignored if the population has no DistanceCache.
+func fingerprintedDistance(pop Population, a Genome, aFingerprint uint64, b Genome, bFingerprint uint64) float64 {
+	distanceFunc := pop.Cfg.DistanceFunc
+	if distanceFunc == nil {
+		distanceFunc = NeatgoDistance
+	}
+	if pop.DistanceCache == nil {
+		return distanceFunc(pop.Cfg, a, b)
+	}
+	if distance, ok := pop.DistanceCache.Get(aFingerprint, bFingerprint); ok {
+		return distance
+	}
+	distance := distanceFunc(pop.Cfg, a, b)
+	pop.DistanceCache.Set(aFingerprint, bFingerprint, distance)
+	return distance
+}
+
+func countExcessAndDisjointGenes(a, b Genome) int {
+	innovationNumCount := make(map[int]int)
+
+	for _, node := range a.Layers.Nodes() {
+		innovationNumCount[node.ID]++
+	}
+	for _, node := range b.Layers.Nodes() {
+		innovationNumCount[node.ID]++
+	}
+	for _, connection := range a.Connections {
+		innovationNumCount[connection.ID]++
+	}
+	for _, connection := range b.Connections {
+		innovationNumCount[connection.ID]++
+	}
+
+	tot := 0
+	for _, count := range innovationNumCount {
+		if count < 2 {
+			tot++
+		}
+	}
+
+	return tot
+}
+
+func calculateAverageConnectionWeightDiff(a, b Genome) float64 {
+	innovationNumCount := make(map[int]int)
+	innovationWeights := make(map[int]float64)
+	for _, connection := range a.Connections {
+		innovationNumCount[connection.ID]++
+		innovationWeights[connection.ID] = connection.Weight
+	}
+	for _, connection := range b.Connections {
+		innovationNumCount[connection.ID]++
+		innovationWeights[connection.ID] -= connection.Weight
+	}
+
+	tot := .0
+	totalWeightDiff := .0
+	for i, count := range innovationNumCount {
+		if count == 2 {
+			tot++
+			totalWeightDiff += math.Abs(innovationWeights[i])
+		}
+	}
+
+	// Avoid divide by zero
+	if tot == 0 {
+		return 100
+	}
+	return totalWeightDiff / tot
+}
+
+func calculateAverageNodeBiasDiff(a, b Genome) float64 {
+	innovationNumCount := make(map[int]int)
+	innovationBiases := make(map[int]float64)
+	for _, layer := range a.Layers {
+		for _, node := range layer {
+			innovationNumCount[node.ID]++
+			innovationBiases[node.ID] = node.Bias
+		}
+	}
+	for _, layer := range b.Layers {
+		for _, node := range layer {
+			innovationNumCount[node.ID]++
+			innovationBiases[node.ID] -= node.Bias
+		}
+	}
+
+	tot := .0
+	totalBiasDiff := .0
+	for i, count := range innovationNumCount {
+		if count == 2 {
+			tot++
+			totalBiasDiff += math.Abs(innovationBiases[i])
+		}
+	}
+
+	// Avoid divide by zero
+	if tot == 0 {
+		return 100
+	}
+	return totalBiasDiff / tot
+}
@@ -0,0 +1,113 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/jmwri/neatgo/network"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func distanceTestGenomes() (neat.Genome, neat.Genome) {
+	layers := [][]network.Node{
+		{
+			{ID: 1, Type: network.Input, ActivationFn: network.NoActivation},
+		},
+		{
+			{ID: 2, Type: network.Output, Bias: 1, ActivationFn: network.Sigmoid},
+		},
+	}
+	a := neat.NewGenome(layers, []network.Connection{
+		{ID: 3, From: 1, To: 2, Weight: 1, Enabled: true},
+	})
+	b := neat.NewGenome(layers, []network.Connection{
+		{ID: 3, From: 1, To: 2, Weight: .5, Enabled: true},
+		{ID: 4, From: 1, To: 2, Weight: 1, Enabled: true},
+	})
+	return a, b
+}
+
+func TestNEATDistance(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.SpeciesCompatExcessCoeff = 1
+	cfg.SpeciesCompatWeightDiffCoeff = 2
+	a, b := distanceTestGenomes()
+	assert.Equal(t, 0.0, neat.NEATDistance(cfg, a, a))
+	// 1 excess gene, N = 1, average weight difference of .5
+	assert.Equal(t, 2.0, neat.NEATDistance(cfg, a, b))
+	assert.Equal(t, 2.0, neat.NEATDistance(cfg, b, a))
+}
+
+func TestNeatPythonDistance(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.SpeciesCompatExcessCoeff = 1
+	cfg.SpeciesCompatWeightDiffCoeff = 2
+	cfg.SpeciesCompatBiasDiffCoeff = 1
+	a, b := distanceTestGenomes()
+	assert.Equal(t, 0.0, neat.NeatPythonDistance(cfg, a, a))
+	// Nodes match exactly. Connections: (2*.5 + 1*1) / 2
+	assert.Equal(t, 1.0, neat.NeatPythonDistance(cfg, a, b))
+}
+
+func TestBehaviouralDistance(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	a, b := distanceTestGenomes()
+	distance := neat.BehaviouralDistance([][]float64{{0}, {1}})
+	assert.Equal(t, 0.0, distance(cfg, a, a))
+	assert.Greater(t, distance(cfg, a, b), 0.0)
+	assert.Equal(t, distance(cfg, a, b), distance(cfg, b, a))
+}
+
+func TestGenome_Fingerprint(t *testing.T) {
+	a, b := distanceTestGenomes()
+	assert.Equal(t, a.Fingerprint(), neat.CopyGenome(a).Fingerprint())
+	assert.NotEqual(t, a.Fingerprint(), b.Fingerprint())
+
+	reordered := neat.CopyGenome(b)
+	reordered.Connections[0], reordered.Connections[1] = reordered.Connections[1], reordered.Connections[0]
+	assert.Equal(t, b.Fingerprint(), reordered.Fingerprint())
+
+	reordered.Connections[0].Weight = 2
+	assert.NotEqual(t, b.Fingerprint(), reordered.Fingerprint())
+}
+
+func TestCompatibleWithSpecies_CachesDistance(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	calls := 0
+	cfg.DistanceFunc = func(cfg neat.Config, a, b neat.Genome) float64 {
+		calls++
+		return 0
+	}
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	a, b := distanceTestGenomes()
+	species := neat.NewSpecies(a)
+	assert.True(t, neat.CompatibleWithSpecies(pop, species, b))
+	assert.True(t, neat.CompatibleWithSpecies(pop, species, b))
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 1, pop.DistanceCache.Len())
+
+	pop.DistanceCache.Reset()
+	assert.True(t, neat.CompatibleWithSpecies(pop, species, b))
+	assert.Equal(t, 2, calls)
+}
+
+func TestSpeciate_CachesDistanceByFingerprint(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 2
+	cfg.DistanceFunc = func(cfg neat.Config, a, b neat.Genome) float64 {
+		return 100
+	}
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	a, b := distanceTestGenomes()
+	pop.Genomes = []neat.Genome{a, b}
+	pop.GenomeFitness = []float64{1, 1}
+	pop.Species = nil
+
+	pop = neat.Speciate(pop)
+	assert.Len(t, pop.Species, 2)
+	distance, ok := pop.DistanceCache.Get(b.Fingerprint(), a.Fingerprint())
+	assert.True(t, ok)
+	assert.Equal(t, 100.0, distance)
+}
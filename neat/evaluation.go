@@ -0,0 +1,153 @@
+package neat
+
+import (
+	"math"
+	"sort"
+)
+
+// Evaluator plays a game with a genome through its ClientGenomeState. It must close the input when the game has
+// finished, and then send the fitness.
+type Evaluator func(state ClientGenomeState)
+
+// EvaluateGeneration runs evaluator concurrently for every GenomeState in the population, then calls RunGeneration.
+func EvaluateGeneration(pop Population, evaluator Evaluator) (Population, error) {
+	for _, state := range pop.States() {
+		go evaluator(state)
+	}
+	return RunGeneration(pop)
+}
+
+// FitnessAggregator combines the repeated evaluations of a genome into a single fitness.
+type FitnessAggregator func(evaluations []float64) float64
+
+// MeanFitness returns the mean of the evaluations.
+func MeanFitness(evaluations []float64) float64 {
+	if len(evaluations) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, evaluation := range evaluations {
+		total += evaluation
+	}
+	return total / float64(len(evaluations))
+}
+
+// MedianFitness returns the median of the evaluations, which ignores occasional outliers.
+func MedianFitness(evaluations []float64) float64 {
+	if len(evaluations) == 0 {
+		return 0
+	}
+	sorted := append([]float64{}, evaluations...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// LowerConfidenceBound returns a FitnessAggregator that scores genomes by mean - z * standard error, so that genomes
+// with inconsistent evaluations are ranked below consistent genomes with the same mean.
+func LowerConfidenceBound(z float64) FitnessAggregator {
+	return func(evaluations []float64) float64 {
+		mean := MeanFitness(evaluations)
+		if len(evaluations) < 2 {
+			return mean
+		}
+		variance := 0.0
+		for _, evaluation := range evaluations {
+			variance += (evaluation - mean) * (evaluation - mean)
+		}
+		variance /= float64(len(evaluations) - 1)
+		return mean - z*math.Sqrt(variance/float64(len(evaluations)))
+	}
+}
+
+func evaluationRepeats(cfg Config) int {
+	if cfg.EvaluationRepeats < 1 {
+		return 1
+	}
+	return cfg.EvaluationRepeats
+}
+
+// combineEvaluations sets the fitness of each genome from the results received from its GenomeStates, and any previous
+// evaluations in pop.FitnessHistory. Objectives, behaviours and case errors are averaged over this generation's
+// evaluations.
+func combineEvaluations(pop Population) Population {
+	numGenomes := len(pop.Genomes)
+	aggregate := pop.Cfg.FitnessAggregator
+	if aggregate == nil {
+		aggregate = MeanFitness
+	}
+	historyLength := pop.Cfg.FitnessHistoryLength
+	if repeats := evaluationRepeats(pop.Cfg); historyLength < repeats {
+		historyLength = repeats
+	}
+
+	evaluations := make([][]float64, numGenomes)
+	for i, fitness := range pop.stateFitness {
+		evaluations[i%numGenomes] = append(evaluations[i%numGenomes], fitness)
+	}
+
+	history := make(map[uint64][]float64)
+	fingerprints := make([]uint64, numGenomes)
+	for i, genome := range pop.Genomes {
+		fingerprints[i] = genome.Fingerprint()
+		previous, ok := history[fingerprints[i]]
+		if !ok && pop.Cfg.FitnessHistoryLength > 0 {
+			// Only genomes that survived into this generation keep their history.
+			previous = pop.FitnessHistory[fingerprints[i]]
+		}
+		// Copies of the same genome share their evaluations.
+		history[fingerprints[i]] = append(append([]float64{}, previous...), evaluations[i]...)
+	}
+	for fingerprint, fitness := range history {
+		if len(fitness) > historyLength {
+			history[fingerprint] = fitness[len(fitness)-historyLength:]
+		}
+	}
+	for i := range pop.Genomes {
+		pop.GenomeFitness[i] = aggregate(history[fingerprints[i]])
+	}
+	pop.FitnessHistory = history
+
+	pop.GenomeObjectives = meanResults(pop.stateObjectives, numGenomes)
+	pop.GenomeBehaviours = meanResults(pop.stateBehaviours, numGenomes)
+	pop.GenomeCaseErrors = meanResults(pop.stateCaseErrors, numGenomes)
+	return pop
+}
+
+// meanResults averages the results received from each GenomeState for the genome they evaluated.
+// A genome's result is nil if any of its GenomeStates didn't send one.
+func meanResults(stateResults [][]float64, numGenomes int) [][]float64 {
+	results := make([][]float64, numGenomes)
+	counts := make([]int, numGenomes)
+	for i, result := range stateResults {
+		genomeIndex := i % numGenomes
+		if result == nil || counts[genomeIndex] < 0 {
+			counts[genomeIndex] = -1
+			continue
+		}
+		if results[genomeIndex] == nil {
+			results[genomeIndex] = make([]float64, len(result))
+		}
+		if len(result) != len(results[genomeIndex]) {
+			counts[genomeIndex] = -1
+			continue
+		}
+		for j, value := range result {
+			results[genomeIndex][j] += value
+		}
+		counts[genomeIndex]++
+	}
+	for i, result := range results {
+		if counts[i] < 0 {
+			results[i] = nil
+			continue
+		}
+		for j := range result {
+			result[j] /= float64(counts[i])
+		}
+	}
+	return results
+}
@@ -0,0 +1,138 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestFitnessAggregators(t *testing.T) {
+	evaluations := []float64{4, 1, 2, 100}
+	assert.InDelta(t, 26.75, neat.MeanFitness(evaluations), 1e-9)
+	assert.InDelta(t, 3, neat.MedianFitness(evaluations), 1e-9)
+	assert.InDelta(t, 2, neat.MedianFitness([]float64{3, 2, 1}), 1e-9)
+	assert.Equal(t, 0., neat.MeanFitness(nil))
+	assert.Equal(t, 0., neat.MedianFitness(nil))
+
+	lcb := neat.LowerConfidenceBound(2)
+	assert.Equal(t, 5., lcb([]float64{5}))
+	assert.InDelta(t, 5, lcb([]float64{5, 5, 5}), 1e-9)
+	// Sample standard deviation of 1, 3 is sqrt(2), so standard error is 1.
+	assert.InDelta(t, 0, lcb([]float64{1, 3}), 1e-9)
+	assert.Less(t, lcb([]float64{0, 10}), lcb([]float64{4, 6}))
+}
+
+func TestRunGeneration_EvaluationRepeats(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	cfg.EvaluationRepeats = 3
+	cfg.FitnessAggregator = neat.MedianFitness
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	assert.Len(t, pop.States(), 30)
+
+	pop, err = runGenerationWithFitness(pop, func(i int) float64 {
+		// The last evaluation of each genome is a lucky outlier.
+		if i >= 20 {
+			return 1000
+		}
+		return float64(i % 10)
+	})
+	assert.NoError(t, err)
+	assert.Len(t, pop.States(), len(pop.Genomes)*3)
+	assert.Equal(t, 9., pop.BestEverGenomeFitness)
+	for fingerprint, history := range pop.FitnessHistory {
+		assert.Len(t, history, 3, "genome %d", fingerprint)
+	}
+}
+
+func TestRunGeneration_FitnessHistory(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	cfg.Elitism = 1
+	cfg.EvaluationRepeats = 2
+	cfg.FitnessHistoryLength = 4
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	evaluations := make(map[uint64]int)
+	for generation := 1; generation <= 4; generation++ {
+		fingerprints := make([]uint64, len(pop.Genomes))
+		for i, genome := range pop.Genomes {
+			fingerprints[i] = genome.Fingerprint()
+		}
+		pop, err = runGenerationWithFitness(pop, func(i int) float64 {
+			return float64(generation)
+		})
+		assert.NoError(t, err)
+
+		previous := evaluations
+		evaluations = make(map[uint64]int)
+		for _, fingerprint := range fingerprints {
+			if _, ok := evaluations[fingerprint]; !ok {
+				evaluations[fingerprint] = previous[fingerprint]
+			}
+			evaluations[fingerprint] += 2
+		}
+		for fingerprint, count := range evaluations {
+			history := pop.FitnessHistory[fingerprint]
+			assert.Len(t, history, int(math.Min(float64(count), 4)))
+			// The most recent evaluation is always last.
+			assert.Equal(t, float64(generation), history[len(history)-1])
+		}
+		assert.Len(t, pop.FitnessHistory, len(evaluations))
+	}
+}
+
+func TestRunGeneration_RepeatedObjectives(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 4
+	cfg.EvaluationRepeats = 2
+	cfg.MultiObjective = true
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	for i, state := range pop.States() {
+		go func(i int, state neat.ClientGenomeState) {
+			close(state.SendInput())
+			state.SendObjectives() <- []float64{float64(i), 1}
+			state.SendFitness() <- 0
+		}(i, state)
+	}
+	pop, err = neat.RunGeneration(pop)
+	assert.NoError(t, err)
+	assert.Len(t, pop.ParetoFront, 1)
+	// Genome 3 is evaluated by states 3 and 7.
+	assert.Equal(t, []float64{5, 1}, pop.ParetoFront[0].Objectives)
+}
+
+func TestEvaluateGeneration(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 20
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		pop, err = neat.EvaluateGeneration(pop, xorEvaluator)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 3, pop.Generation)
+	assert.Greater(t, pop.BestEverGenomeFitness, 0.)
+}
+
+func TestNextGeneration(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 20
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	for i := range pop.GenomeFitness {
+		pop.GenomeFitness[i] = float64(i)
+	}
+	best := pop.Genomes[19]
+	pop, err = neat.NextGeneration(pop)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pop.Generation)
+	assert.Equal(t, 19., pop.BestEverGenomeFitness)
+	assert.Equal(t, best.Fingerprint(), pop.BestEverGenome.Fingerprint())
+	assert.Len(t, pop.States(), 20)
+}
@@ -0,0 +1,220 @@
+package neat
+
+import (
+	"math"
+	"sort"
+)
+
+// FitnessTransform maps the fitness of every genome in a population to a new fitness.
+// The returned slice must be the same length as fitness, and should preserve the order of genomes by fitness.
+type FitnessTransform func(fitness []float64) []float64
+
+// MinShiftFitness shifts all fitness so that the lowest is zero, if any fitness is negative.
+// Populations with no negative fitness are unchanged. NaN and -Inf fitness are shifted to zero, and don't move the
+// rest of the population.
+func MinShiftFitness(fitness []float64) []float64 {
+	shifted := make([]float64, len(fitness))
+	minFitness := 0.0
+	for _, f := range fitness {
+		if !math.IsNaN(f) && !math.IsInf(f, -1) {
+			minFitness = math.Min(minFitness, f)
+		}
+	}
+	for i, f := range fitness {
+		shifted[i] = f - minFitness
+		if math.IsNaN(f) || math.IsInf(f, -1) {
+			shifted[i] = 0
+		}
+	}
+	return shifted
+}
+
+// RankFitness replaces fitness with its rank in the population, from 1 for the lowest fitness to len(fitness) for the
+// highest. Genomes with equal fitness share the average of their ranks, and NaN fitness ranks lowest.
+func RankFitness(fitness []float64) []float64 {
+	order := make([]int, len(fitness))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return fitnessLess(fitness[order[i]], fitness[order[j]])
+	})
+	ranked := make([]float64, len(fitness))
+	for i := 0; i < len(order); {
+		j := i + 1
+		for j < len(order) && fitnessEqual(fitness[order[j]], fitness[order[i]]) {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for ; i < j; i++ {
+			ranked[order[i]] = rank
+		}
+	}
+	return ranked
+}
+
+// MinMaxFitness scales fitness linearly so the lowest is 0 and the highest is 1.
+// If every genome has the same fitness they all get 0. NaN fitness gets 0.
+func MinMaxFitness(fitness []float64) []float64 {
+	scaled := make([]float64, len(fitness))
+	minFitness, maxFitness := math.Inf(1), math.Inf(-1)
+	for _, f := range fitness {
+		if !math.IsNaN(f) {
+			minFitness = math.Min(minFitness, f)
+			maxFitness = math.Max(maxFitness, f)
+		}
+	}
+	if maxFitness <= minFitness {
+		return scaled
+	}
+	for i, f := range fitness {
+		scaled[i] = (f - minFitness) / (maxFitness - minFitness)
+	}
+	return fillNaNFitness(fitness, scaled)
+}
+
+// RankUtilityFitness replaces fitness with the rank-based utility used by natural evolution strategies. Only the fittest
+// half of the population get a positive utility, which decreases logarithmically with rank, and utilities sum to zero.
+// Genomes with equal fitness share the average of their utilities, and NaN fitness ranks lowest.
+func RankUtilityFitness(fitness []float64) []float64 {
+	n := len(fitness)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return fitnessLess(fitness[order[j]], fitness[order[i]])
+	})
+
+	utilities := make([]float64, n)
+	utilitySum := 0.0
+	for rank := range utilities {
+		utilities[rank] = math.Max(0, math.Log(float64(n)/2+1)-math.Log(float64(rank+1)))
+		utilitySum += utilities[rank]
+	}
+	shaped := make([]float64, n)
+	for i := 0; i < n; {
+		j := i
+		tiedUtility := 0.0
+		for j < n && fitnessEqual(fitness[order[j]], fitness[order[i]]) {
+			tiedUtility += utilities[j]/utilitySum - 1/float64(n)
+			j++
+		}
+		tied := float64(j - i)
+		for ; i < j; i++ {
+			shaped[order[i]] = tiedUtility / tied
+		}
+	}
+	return shaped
+}
+
+// ZScoreFitness replaces fitness with the number of standard deviations it is from the mean.
+// If every genome has the same fitness they all get 0. NaN fitness is left out of the mean and standard deviation, and
+// gets the lowest score.
+func ZScoreFitness(fitness []float64) []float64 {
+	shaped := make([]float64, len(fitness))
+	count := 0
+	mean := 0.0
+	for _, f := range fitness {
+		if !math.IsNaN(f) {
+			count++
+			mean += f
+		}
+	}
+	if count == 0 {
+		return shaped
+	}
+	mean /= float64(count)
+	variance := 0.0
+	for _, f := range fitness {
+		if !math.IsNaN(f) {
+			variance += (f - mean) * (f - mean)
+		}
+	}
+	if variance == 0 {
+		return shaped
+	}
+	std := math.Sqrt(variance / float64(count))
+	for i, f := range fitness {
+		shaped[i] = (f - mean) / std
+	}
+	return fillNaNFitness(fitness, shaped)
+}
+
+// LogFitness compresses fitness logarithmically, keeping its sign, so that a few very fit genomes don't dominate.
+// NaN fitness gets the lowest compressed fitness.
+func LogFitness(fitness []float64) []float64 {
+	shaped := make([]float64, len(fitness))
+	for i, f := range fitness {
+		shaped[i] = math.Copysign(math.Log1p(math.Abs(f)), f)
+	}
+	return fillNaNFitness(fitness, shaped)
+}
+
+// fitnessLess orders fitness ascending, with NaN lower than any other fitness so that a failed evaluation can't break
+// sorting.
+func fitnessLess(a, b float64) bool {
+	return (math.IsNaN(a) && !math.IsNaN(b)) || a < b
+}
+
+// fitnessEqual reports whether two fitnesses tie, where NaN ties with NaN.
+func fitnessEqual(a, b float64) bool {
+	return a == b || (math.IsNaN(a) && math.IsNaN(b))
+}
+
+// fillNaNFitness gives every genome with NaN fitness the lowest of the other genomes' shaped fitness, or 0 if all
+// fitness is NaN.
+func fillNaNFitness(fitness, shaped []float64) []float64 {
+	lowest := math.Inf(1)
+	for i, f := range fitness {
+		if !math.IsNaN(f) {
+			lowest = math.Min(lowest, shaped[i])
+		}
+	}
+	if math.IsInf(lowest, 1) {
+		lowest = 0
+	}
+	for i, f := range fitness {
+		if math.IsNaN(f) {
+			shaped[i] = lowest
+		}
+	}
+	return shaped
+}
+
+// ShapeFitness applies Cfg.FitnessShaping to the population's fitness. RunGeneration calls it after evaluation and
+// before speciation, once the raw fitness has been kept in RawFitness for the best genome, hall of fame and species
+// statistics.
+func ShapeFitness(pop Population) Population {
+	if pop.Cfg.FitnessShaping == nil {
+		return pop
+	}
+	pop.GenomeFitness = pop.Cfg.FitnessShaping(pop.GenomeFitness)
+	return pop
+}
+
+// NormaliseFitness applies Cfg.FitnessNormalisation to the population's fitness, so that offspring allocation and
+// parent selection can treat fitness as non-negative.
+func NormaliseFitness(pop Population) Population {
+	if pop.Cfg.FitnessNormalisation == nil {
+		return pop
+	}
+	pop.GenomeFitness = pop.Cfg.FitnessNormalisation(pop.GenomeFitness)
+	return pop
+}
+
+// recordBestGenome updates the best genome of this generation, and of all time, from the fitness of each genome.
+func recordBestGenome(pop Population, genomeFitness []float64) Population {
+	pop.BestGenomeFitness = math.Inf(-1)
+	for genomeIndex, fitness := range genomeFitness {
+		if fitness > pop.BestGenomeFitness {
+			pop.BestGenomeFitness = fitness
+			pop.BestGenome = pop.Genomes[genomeIndex]
+		}
+	}
+	if pop.BestGenomeFitness > pop.BestEverGenomeFitness {
+		pop.BestEverGenomeFitness = pop.BestGenomeFitness
+		pop.BestEverGenome = pop.BestGenome
+	}
+	return pop
+}
@@ -0,0 +1,153 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestMinShiftFitness(t *testing.T) {
+	assert.Equal(t, []float64{0, 5, 9}, neat.MinShiftFitness([]float64{-10, -5, -1}))
+	assert.Equal(t, []float64{1, 5, 9}, neat.MinShiftFitness([]float64{1, 5, 9}))
+	assert.Equal(t, []float64{}, neat.MinShiftFitness([]float64{}))
+	assert.Equal(t, []float64{0, 0, 3}, neat.MinShiftFitness([]float64{-1, math.NaN(), 2}))
+	assert.Equal(t, []float64{0, 0, 3}, neat.MinShiftFitness([]float64{-1, math.Inf(-1), 2}))
+}
+
+func TestRankFitness(t *testing.T) {
+	assert.Equal(t, []float64{1, 3, 2}, neat.RankFitness([]float64{-10, 4, -1}))
+	assert.Equal(t, []float64{2, 2, 2}, neat.RankFitness([]float64{0, 0, 0}))
+	assert.Equal(t, []float64{1, 2.5, 2.5, 4}, neat.RankFitness([]float64{1, 2, 2, 3}))
+	assert.Equal(t, []float64{3, 1.5, 4, 1.5}, neat.RankFitness([]float64{1, math.NaN(), 2, math.NaN()}))
+}
+
+func TestSpeciate_NegativeFitnessImproves(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 4
+	cfg.SpeciesCompatThreshold = 1000
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	pop.GenomeFitness = []float64{-10, -8, -6, -4}
+	pop = neat.Speciate(pop)
+	assert.Len(t, pop.Species, 1)
+	assert.Equal(t, -4.0, pop.Species[0].BestFitness)
+	assert.Equal(t, 0, pop.Species[0].Staleness)
+
+	pop.GenomeFitness = []float64{-10, -8, -6, -3}
+	pop = neat.Speciate(pop)
+	assert.Equal(t, -3.0, pop.Species[0].BestFitness)
+	assert.Equal(t, 0, pop.Species[0].Staleness)
+
+	pop.GenomeFitness = []float64{-10, -8, -6, -5}
+	pop = neat.Speciate(pop)
+	assert.Equal(t, 1, pop.Species[0].Staleness)
+}
+
+func TestMinMaxFitness(t *testing.T) {
+	assert.Equal(t, []float64{0, .5, 1}, neat.MinMaxFitness([]float64{-2, 0, 2}))
+	assert.Equal(t, []float64{0, 0}, neat.MinMaxFitness([]float64{3, 3}))
+	assert.Equal(t, []float64{0, 0, 1}, neat.MinMaxFitness([]float64{1, math.NaN(), 3}))
+}
+
+func TestRankUtilityFitness(t *testing.T) {
+	utilities := neat.RankUtilityFitness([]float64{3, 1000, -5, 2})
+	sum := 0.0
+	for _, utility := range utilities {
+		sum += utility
+	}
+	assert.InDelta(t, 0, sum, 1e-9)
+	// Only the fittest half have a positive utility, and the scale of fitness doesn't matter.
+	assert.Greater(t, utilities[1], utilities[0])
+	assert.Greater(t, utilities[0], 0.)
+	assert.InDelta(t, -.25, utilities[3], 1e-9)
+	assert.InDelta(t, -.25, utilities[2], 1e-9)
+	assert.Equal(t, utilities, neat.RankUtilityFitness([]float64{3, 4, -5, 2}))
+
+	tied := neat.RankUtilityFitness([]float64{1, 1})
+	assert.InDelta(t, tied[0], tied[1], 1e-9)
+	tied = neat.RankUtilityFitness([]float64{5, 5, 5, 1})
+	assert.InDelta(t, tied[0], tied[1], 1e-9)
+	assert.InDelta(t, tied[0], tied[2], 1e-9)
+	assert.InDelta(t, 0, tied[0]*3+tied[3], 1e-9)
+
+	withNaN := neat.RankUtilityFitness([]float64{math.NaN(), 2, math.NaN(), 1})
+	assert.InDelta(t, withNaN[0], withNaN[2], 1e-9)
+	assert.Less(t, withNaN[0], withNaN[3])
+	assert.Greater(t, withNaN[1], 0.)
+}
+
+func TestZScoreFitness(t *testing.T) {
+	assert.InDeltaSlice(t, []float64{-1, 1}, neat.ZScoreFitness([]float64{10, 20}), 1e-9)
+	assert.InDeltaSlice(t, []float64{-1.2247, 0, 1.2247}, neat.ZScoreFitness([]float64{1, 2, 3}), 1e-4)
+	assert.Equal(t, []float64{0, 0}, neat.ZScoreFitness([]float64{5, 5}))
+	assert.InDeltaSlice(t, []float64{-1, -1, 1}, neat.ZScoreFitness([]float64{10, math.NaN(), 20}), 1e-9)
+	assert.Equal(t, []float64{0, 0}, neat.ZScoreFitness([]float64{math.NaN(), math.NaN()}))
+}
+
+func TestLogFitness(t *testing.T) {
+	assert.InDeltaSlice(t, []float64{0, math.Log(2), -math.Log(11)}, neat.LogFitness([]float64{0, 1, -10}), 1e-9)
+	assert.InDeltaSlice(t, []float64{math.Log(2), -math.Log(11), -math.Log(11)}, neat.LogFitness([]float64{1, -10, math.NaN()}), 1e-9)
+}
+
+func TestRunGeneration_FitnessShaping(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 20
+	cfg.FitnessShaping = neat.RankUtilityFitness
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	pop, err = runGenerationWithFitness(pop, func(i int) float64 {
+		return float64(i * 1000)
+	})
+	assert.NoError(t, err)
+	// Statistics use the raw fitness.
+	assert.Equal(t, 19000., pop.BestEverGenomeFitness)
+	assert.Len(t, pop.RawFitness, 20)
+	assert.Equal(t, 19000., pop.RawFitness[19])
+	for _, species := range pop.Species {
+		history := pop.SpeciesHistory[species.ID]
+		assert.Equal(t, 0., math.Mod(history[len(history)-1].BestFitness, 1000))
+	}
+	assertSpeciesCoverPopulation(t, pop)
+}
+
+func TestRunGeneration_FitnessShapingStaleness(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	cfg.SpeciesCompatThreshold = 1000
+	cfg.FitnessShaping = neat.MinMaxFitness
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	// Raw fitness improves every generation, but shaped fitness is always between 0 and 1.
+	for generation := 1; generation <= 3; generation++ {
+		pop, err = runGenerationWithFitness(pop, func(i int) float64 {
+			return float64(generation*100 + i)
+		})
+		assert.NoError(t, err)
+		assert.Len(t, pop.Species, 1)
+		assert.Equal(t, 0, pop.Species[0].Staleness)
+		history := pop.SpeciesHistory[pop.Species[0].ID]
+		assert.Equal(t, float64(generation*100+9), history[len(history)-1].BestFitness)
+	}
+}
+
+func TestRunGeneration_RankFitnessNaN(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	cfg.FitnessNormalisation = neat.RankFitness
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	pop, err = runGenerationWithFitness(pop, func(i int) float64 {
+		if i%2 == 0 {
+			return math.NaN()
+		}
+		return float64(i)
+	})
+	assert.NoError(t, err)
+	assert.Len(t, pop.Genomes, 10)
+	assertSpeciesCoverPopulation(t, pop)
+}
@@ -1,9 +1,13 @@
 package neat
 
 import (
+	"encoding/binary"
 	"fmt"
 	"github.com/jmwri/neatgo/network"
 	"github.com/jmwri/neatgo/util"
+	"hash/fnv"
+	"math"
+	"sort"
 )
 
 type Layers [][]network.Node
@@ -35,6 +39,52 @@ func (g Genome) NumConnections() int {
 	return len(g.Connections)
 }
 
+// Activate runs input through the genome's network.
+func (g Genome) Activate(input []float64) ([]float64, error) {
+	return network.Activate(g.Layers.Nodes(), g.Connections, input)
+}
+
+// Fingerprint returns a hash of the genome's genes. Genomes with the same nodes and connections have the same
+// fingerprint, regardless of the order of their genes.
+func (g Genome) Fingerprint() uint64 {
+	nodes := g.Layers.Nodes()
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ID < nodes[j].ID
+	})
+	connections := make([]network.Connection, len(g.Connections))
+	copy(connections, g.Connections)
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].ID < connections[j].ID
+	})
+
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	writeUint64 := func(v uint64) {
+		binary.LittleEndian.PutUint64(buf, v)
+		h.Write(buf)
+	}
+	for _, node := range nodes {
+		writeUint64(uint64(node.ID))
+		h.Write([]byte(node.Type))
+		writeUint64(math.Float64bits(node.Bias))
+		h.Write([]byte(node.ActivationFn))
+	}
+	// Separate nodes from connections so that the gene types can't be confused.
+	h.Write([]byte{0})
+	for _, connection := range connections {
+		writeUint64(uint64(connection.ID))
+		writeUint64(uint64(connection.From))
+		writeUint64(uint64(connection.To))
+		writeUint64(math.Float64bits(connection.Weight))
+		if connection.Enabled {
+			h.Write([]byte{1})
+		} else {
+			h.Write([]byte{0})
+		}
+	}
+	return h.Sum64()
+}
+
 func NewGenome(layers [][]network.Node, connections []network.Connection) Genome {
 	return Genome{
 		Layers:      layers,
@@ -169,7 +219,6 @@ func getNodeLayer(layers [][]network.Node, nodeID int) int {
 }
 
 func Crossover(cfg Config, best, worst Genome) Genome {
-	childLayers := make(Layers, len(best.Layers))
 	childConnections := make([]network.Connection, 0)
 
 	// Count the number of innovations in each genome
@@ -218,53 +267,20 @@ func Crossover(cfg Config, best, worst Genome) Genome {
 		}
 	}
 
-	bestNodes := make(map[int]network.Node)
-	bestNodesLayer := make(map[int]int)
-	for layerNum, layer := range best.Layers {
-		for _, node := range layer {
-			bestNodes[node.ID] = node
-			bestNodesLayer[node.ID] = layerNum
-		}
-	}
-	bestConnections := make(map[int]network.Connection)
-	for _, connection := range best.Connections {
-		bestConnections[connection.ID] = connection
-	}
-	worstNodes := make(map[int]network.Node)
-	worstNodesLayer := make(map[int]int)
-	for layerNum, layer := range worst.Layers {
-		for _, node := range layer {
-			worstNodes[node.ID] = node
-			worstNodesLayer[node.ID] = layerNum
-		}
-	}
-	worstConnections := make(map[int]network.Connection)
-	for _, connection := range worst.Connections {
-		worstConnections[connection.ID] = connection
-	}
-
-	// Add each node that is chosen from best
+	// Gather each node that is chosen from best or worst. Layers are rebuilt from the child's connections below, so
+	// the parents' layer indices are only used to keep a stable node order.
+	childNodes := make([]network.Node, 0)
 	for _, bestLayer := range best.Layers {
 		for _, bestNode := range bestLayer {
-			parentChoice := innovationParentChoice[bestNode.ID]
-			layer := bestNodesLayer[bestNode.ID]
-			if parentChoice == 1 {
-				childLayers[layer] = append(childLayers[layer], bestNode)
+			if innovationParentChoice[bestNode.ID] == 1 {
+				childNodes = append(childNodes, bestNode)
 			}
 		}
 	}
-
-	// Add each node that is chosen from worst
 	for _, worstLayer := range worst.Layers {
 		for _, worstNode := range worstLayer {
-			parentChoice := innovationParentChoice[worstNode.ID]
-			layer := worstNodesLayer[worstNode.ID]
-			// If the node exists in best, take the layer of best instead to preserve and structural changes.
-			if bestLayer, ok := bestNodesLayer[worstNode.ID]; ok {
-				layer = bestLayer
-			}
-			if parentChoice == 2 {
-				childLayers[layer] = append(childLayers[layer], worstNode)
+			if innovationParentChoice[worstNode.ID] == 2 {
+				childNodes = append(childNodes, worstNode)
 			}
 		}
 	}
@@ -284,8 +300,113 @@ func Crossover(cfg Config, best, worst Genome) Genome {
 		}
 	}
 
+	return buildLayeredGenome(childNodes, childConnections)
+}
+
+// buildLayeredGenome arranges nodes into layers using a topological sort of the connections between them.
+// Input and bias nodes are placed in the first layer, output nodes in the last, and each hidden node one layer after
+// the deepest node feeding into it. Connections to missing nodes, into input or bias nodes, out of output nodes, or
+// that would create a cycle are dropped.
+func buildLayeredGenome(nodes []network.Node, connections []network.Connection) Genome {
+	nodesByID := make(map[int]network.Node)
+	for _, node := range nodes {
+		nodesByID[node.ID] = node
+	}
+
+	// Keep connections in order, skipping any that can't be evaluated by a feed-forward network.
+	keptConnections := make([]network.Connection, 0)
+	connectionIDs := make(map[int]bool)
+	outgoing := make(map[int][]int)
+	for _, connection := range connections {
+		if connectionIDs[connection.ID] {
+			continue
+		}
+		from, fromOk := nodesByID[connection.From]
+		to, toOk := nodesByID[connection.To]
+		if !fromOk || !toOk || connection.From == connection.To {
+			continue
+		}
+		if from.Type == network.Output || to.Type == network.Input || to.Type == network.Bias {
+			continue
+		}
+		if nodeReachable(outgoing, connection.To, connection.From) {
+			continue
+		}
+		connectionIDs[connection.ID] = true
+		outgoing[connection.From] = append(outgoing[connection.From], connection.To)
+		keptConnections = append(keptConnections, connection)
+	}
+
+	// Work out the depth of each node, as the longest path from any node without inputs.
+	// Hidden nodes always sit after the input layer, even if nothing connects to them.
+	incoming := make(map[int]int)
+	for _, connection := range keptConnections {
+		incoming[connection.To]++
+	}
+	depths := make(map[int]int)
+	queue := make([]int, 0)
+	for _, node := range nodes {
+		if node.Type == network.Hidden {
+			depths[node.ID] = 1
+		}
+		if incoming[node.ID] == 0 {
+			queue = append(queue, node.ID)
+		}
+	}
+	for len(queue) > 0 {
+		nodeID := queue[0]
+		queue = queue[1:]
+		for _, toID := range outgoing[nodeID] {
+			if depths[nodeID]+1 > depths[toID] {
+				depths[toID] = depths[nodeID] + 1
+			}
+			incoming[toID]--
+			if incoming[toID] == 0 {
+				queue = append(queue, toID)
+			}
+		}
+	}
+
+	maxHiddenDepth := 0
+	for _, node := range nodes {
+		if node.Type == network.Hidden && depths[node.ID] > maxHiddenDepth {
+			maxHiddenDepth = depths[node.ID]
+		}
+	}
+
+	layers := make(Layers, maxHiddenDepth+2)
+	for _, node := range nodes {
+		layer := depths[node.ID]
+		switch node.Type {
+		case network.Input, network.Bias:
+			layer = 0
+		case network.Output:
+			layer = maxHiddenDepth + 1
+		}
+		layers[layer] = append(layers[layer], node)
+	}
+
 	return Genome{
-		Layers:      childLayers,
-		Connections: childConnections,
+		Layers:      layers,
+		Connections: keptConnections,
+	}
+}
+
+// nodeReachable reports whether there is a path from one node to another through the outgoing connections.
+func nodeReachable(outgoing map[int][]int, from, to int) bool {
+	visited := make(map[int]bool)
+	stack := []int{from}
+	for len(stack) > 0 {
+		nodeID := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if nodeID == to {
+			return true
+		}
+		if visited[nodeID] {
+			continue
+		}
+		visited[nodeID] = true
+		stack = append(stack, outgoing[nodeID]...)
 	}
+	return false
 }
@@ -0,0 +1,69 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/jmwri/neatgo/network"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCrossover_RebuildsLayers(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	// Always take shared genes from worst.
+	cfg.MateBestRate = 0
+
+	nodes := []network.Node{
+		{ID: 1, Type: network.Input, ActivationFn: network.NoActivation},
+		{ID: 2, Type: network.Output, ActivationFn: network.Sigmoid},
+		{ID: 3, Type: network.Hidden, ActivationFn: network.Sigmoid},
+		{ID: 4, Type: network.Hidden, ActivationFn: network.Sigmoid},
+	}
+	best := neat.NewGenome(
+		[][]network.Node{{nodes[0]}, {nodes[2]}, {nodes[3]}, {nodes[1]}},
+		[]network.Connection{
+			{ID: 10, From: 1, To: 3, Weight: 1, Enabled: true},
+			{ID: 11, From: 3, To: 4, Weight: 1, Enabled: true},
+			{ID: 12, From: 4, To: 2, Weight: 1, Enabled: true},
+			{ID: 13, From: 3, To: 2, Weight: 1, Enabled: true},
+		},
+	)
+	// Worst shares the same innovation IDs, but has reversed the hidden connection and points another at a node that
+	// doesn't exist in the child.
+	worst := neat.NewGenome(
+		[][]network.Node{{nodes[0]}, {nodes[3]}, {nodes[2]}, {nodes[1]}},
+		[]network.Connection{
+			{ID: 10, From: 1, To: 3, Weight: 1, Enabled: true},
+			{ID: 11, From: 4, To: 3, Weight: 1, Enabled: true},
+			{ID: 12, From: 4, To: 2, Weight: 1, Enabled: true},
+			{ID: 13, From: 3, To: 99, Weight: 1, Enabled: true},
+		},
+	)
+
+	child := neat.Crossover(cfg, best, worst)
+	assert.Equal(t, 4, child.NumNodes())
+	assert.Equal(t, 3, child.NumConnections())
+	assert.Equal(t, 4, child.NumLayers())
+	assert.Empty(t, neat.ValidateGenome(cfg, child))
+
+	_, err := network.Activate(child.Layers.Nodes(), child.Connections, []float64{1})
+	assert.NoError(t, err)
+}
+
+func TestCrossover_EvolvedParents(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 2)
+	cfg.AddNodeMutationRate = .5
+	cfg.AddConnectionMutationRate = .5
+	ancestor, err := neat.GenerateGenome(cfg)
+	assert.NoError(t, err, "unexpected error when generating genome")
+
+	for i := 0; i < 50; i++ {
+		a := ancestor
+		b := ancestor
+		for j := 0; j < 10; j++ {
+			a = neat.MutateGenome(cfg, a)
+			b = neat.MutateGenome(cfg, b)
+		}
+		child := neat.Crossover(cfg, a, b)
+		assert.Empty(t, neat.ValidateGenome(cfg, child))
+	}
+}
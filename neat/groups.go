@@ -0,0 +1,178 @@
+package neat
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// GroupEvaluator runs a shared environment containing every genome in the group, and returns the fitness of each
+// member in the same order. Groups are evaluated concurrently.
+type GroupEvaluator func(group []Genome) ([]float64, error)
+
+type GroupSampling string
+
+const (
+	// RandomGroups puts genomes into groups at random.
+	RandomGroups GroupSampling = "random"
+	// MixedSpeciesGroups spreads the members of each species across groups, so groups contain as many species as
+	// possible.
+	MixedSpeciesGroups GroupSampling = "mixed-species"
+)
+
+type GroupConfig struct {
+	GroupSize       int           // How many genomes to put in each group.
+	GroupsPerGenome int           // How many groups each genome is evaluated in. Results are combined with Cfg.FitnessAggregator.
+	Sampling        GroupSampling // How to choose the members of each group.
+	Workers         int           // How many groups to evaluate at the same time. 0 uses the number of CPUs.
+}
+
+func DefaultGroupConfig(groupSize int) GroupConfig {
+	return GroupConfig{
+		GroupSize:       groupSize,
+		GroupsPerGenome: 3,
+		Sampling:        MixedSpeciesGroups,
+		Workers:         0,
+	}
+}
+
+// EvaluateGroupGeneration evaluates the population in groups with evaluator, sets the fitness of each genome from
+// every group it was in, and breeds the next generation with NextGeneration.
+func EvaluateGroupGeneration(pop Population, cfg GroupConfig, evaluator GroupEvaluator) (Population, error) {
+	if cfg.GroupSize < 1 || cfg.GroupSize > len(pop.Genomes) {
+		return pop, fmt.Errorf("group size %d is invalid for %d genomes", cfg.GroupSize, len(pop.Genomes))
+	}
+	report(pop, func(r Reporter) {
+		r.GenerationStart(pop)
+	})
+	groups := SampleGroups(pop, cfg)
+	results := make([][]float64, len(groups))
+	errs := make([]error, len(groups))
+
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	groupIndexes := make(chan int)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for g := range groupIndexes {
+				members := make([]Genome, len(groups[g]))
+				for i, genomeIndex := range groups[g] {
+					members[i] = pop.Genomes[genomeIndex]
+				}
+				results[g], errs[g] = evaluator(members)
+			}
+		}()
+	}
+	for g := range groups {
+		groupIndexes <- g
+	}
+	close(groupIndexes)
+	wg.Wait()
+
+	evaluations := make([][]float64, len(pop.Genomes))
+	for g, group := range groups {
+		if errs[g] != nil {
+			return pop, fmt.Errorf("failed to evaluate group %d: %w", g, errs[g])
+		}
+		if len(results[g]) != len(group) {
+			return pop, fmt.Errorf("group %d has %d members, evaluator returned %d results", g, len(group), len(results[g]))
+		}
+		for i, genomeIndex := range group {
+			evaluations[genomeIndex] = append(evaluations[genomeIndex], results[g][i])
+		}
+	}
+
+	aggregate := pop.Cfg.FitnessAggregator
+	if aggregate == nil {
+		aggregate = MeanFitness
+	}
+	for i := range pop.Genomes {
+		pop.GenomeFitness[i] = aggregate(evaluations[i])
+	}
+	return NextGeneration(pop)
+}
+
+// SampleGroups returns the genome indexes of each group to evaluate. Every genome is put in cfg.GroupsPerGenome groups.
+// If the population doesn't divide into groups evenly, the last group of each round is filled with random genomes from
+// earlier groups, which are then evaluated an extra time.
+func SampleGroups(pop Population, cfg GroupConfig) [][]int {
+	groups := make([][]int, 0)
+	if cfg.GroupSize < 1 || len(pop.Genomes) == 0 {
+		return groups
+	}
+	rounds := cfg.GroupsPerGenome
+	if rounds < 1 {
+		rounds = 1
+	}
+	for round := 0; round < rounds; round++ {
+		var order []int
+		if cfg.Sampling == MixedSpeciesGroups && len(pop.Species) > 0 {
+			order = mixedSpeciesOrder(pop)
+		} else {
+			order = rand.Perm(len(pop.Genomes))
+		}
+		for start := 0; start < len(order); start += cfg.GroupSize {
+			end := start + cfg.GroupSize
+			if end > len(order) {
+				end = len(order)
+			}
+			group := append([]int{}, order[start:end]...)
+			inGroup := make(map[int]bool)
+			for _, genomeIndex := range group {
+				inGroup[genomeIndex] = true
+			}
+			for _, genomeIndex := range rand.Perm(len(order)) {
+				if len(group) >= cfg.GroupSize {
+					break
+				}
+				if !inGroup[order[genomeIndex]] {
+					group = append(group, order[genomeIndex])
+					inGroup[order[genomeIndex]] = true
+				}
+			}
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// mixedSpeciesOrder orders genomes by taking one from each species in turn, so that consecutive genomes are from
+// different species where possible.
+func mixedSpeciesOrder(pop Population) []int {
+	members := make([][]int, 0, len(pop.Species))
+	inSpecies := make(map[int]bool)
+	for _, speciesIndex := range rand.Perm(len(pop.Species)) {
+		genomes := append([]int{}, pop.Species[speciesIndex].Genomes...)
+		rand.Shuffle(len(genomes), func(i, j int) {
+			genomes[i], genomes[j] = genomes[j], genomes[i]
+		})
+		for _, genomeIndex := range genomes {
+			inSpecies[genomeIndex] = true
+		}
+		members = append(members, genomes)
+	}
+	// Genomes without a species are treated as their own species.
+	for i := range pop.Genomes {
+		if !inSpecies[i] {
+			members = append(members, []int{i})
+		}
+	}
+
+	order := make([]int, 0, len(pop.Genomes))
+	for len(order) < len(pop.Genomes) {
+		for s := range members {
+			if len(members[s]) == 0 {
+				continue
+			}
+			order = append(order, members[s][0])
+			members[s] = members[s][1:]
+		}
+	}
+	return order
+}
@@ -0,0 +1,111 @@
+package neat_test
+
+import (
+	"errors"
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSampleGroups(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 10
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	pop = neat.Speciate(pop)
+
+	for _, sampling := range []neat.GroupSampling{neat.RandomGroups, neat.MixedSpeciesGroups} {
+		t.Run(string(sampling), func(t *testing.T) {
+			groups := neat.SampleGroups(pop, neat.GroupConfig{
+				GroupSize:       4,
+				GroupsPerGenome: 2,
+				Sampling:        sampling,
+			})
+			// 10 genomes need 3 groups of 4 each round.
+			assert.Len(t, groups, 6)
+			counts := make(map[int]int)
+			for _, group := range groups {
+				assert.Len(t, group, 4)
+				members := make(map[int]bool)
+				for _, genomeIndex := range group {
+					assert.False(t, members[genomeIndex], "genome %d is in a group twice", genomeIndex)
+					members[genomeIndex] = true
+					counts[genomeIndex]++
+				}
+			}
+			for i := range pop.Genomes {
+				assert.GreaterOrEqual(t, counts[i], 2)
+			}
+		})
+	}
+}
+
+func TestSampleGroups_MixedSpecies(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 6
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	pop.Species = []neat.Species{
+		{ID: 1, Genomes: []int{0, 1, 2}},
+		{ID: 2, Genomes: []int{3, 4, 5}},
+	}
+	groups := neat.SampleGroups(pop, neat.GroupConfig{
+		GroupSize:       2,
+		GroupsPerGenome: 1,
+		Sampling:        neat.MixedSpeciesGroups,
+	})
+	assert.Len(t, groups, 3)
+	for _, group := range groups {
+		// Each pair has one genome from each species.
+		assert.NotEqual(t, group[0] < 3, group[1] < 3, "group %v", group)
+	}
+}
+
+func TestEvaluateGroupGeneration(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 12
+	cfg.FitnessAggregator = neat.MeanFitness
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	groupCfg := neat.GroupConfig{
+		GroupSize:       3,
+		GroupsPerGenome: 2,
+		Sampling:        neat.MixedSpeciesGroups,
+		Workers:         2,
+	}
+	for i := 0; i < 3; i++ {
+		pop, err = neat.EvaluateGroupGeneration(pop, groupCfg, func(group []neat.Genome) ([]float64, error) {
+			// Every member scores the number of members in the group.
+			fitness := make([]float64, len(group))
+			for i := range fitness {
+				fitness[i] = float64(len(group))
+			}
+			return fitness, nil
+		})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 3, pop.Generation)
+	assert.Equal(t, 3., pop.BestEverGenomeFitness)
+	assertSpeciesCoverPopulation(t, pop)
+}
+
+func TestEvaluateGroupGeneration_Errors(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 6
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	_, err = neat.EvaluateGroupGeneration(pop, neat.DefaultGroupConfig(2), func(group []neat.Genome) ([]float64, error) {
+		return nil, errors.New("simulation failed")
+	})
+	assert.Error(t, err)
+
+	_, err = neat.EvaluateGroupGeneration(pop, neat.DefaultGroupConfig(2), func(group []neat.Genome) ([]float64, error) {
+		return []float64{1}, nil
+	})
+	assert.Error(t, err)
+
+	_, err = neat.EvaluateGroupGeneration(pop, neat.DefaultGroupConfig(7), nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package neat
+
+import (
+	"fmt"
+	"github.com/jmwri/neatgo/network"
+	"sort"
+)
+
+// HallOfFameEntry is one of the best distinct genomes seen during evolution.
+type HallOfFameEntry struct {
+	Genome      Genome
+	Fitness     float64
+	Generation  int
+	SpeciesID   int
+	Fingerprint uint64
+}
+
+// HallOfFameGenomes returns the genomes in the hall of fame, fittest first.
+func (p Population) HallOfFameGenomes() []Genome {
+	genomes := make([]Genome, len(p.HallOfFame))
+	for i, entry := range p.HallOfFame {
+		genomes[i] = entry.Genome
+	}
+	return genomes
+}
+
+// UpdateHallOfFame offers every genome in the population to the hall of fame, using fitness as each genome's score.
+// Genomes are identified by their fingerprint, so a genome already in the hall of fame only has its entry replaced if
+// it scored higher. The hall of fame keeps the best Cfg.HallOfFameSize entries, fittest first.
+func UpdateHallOfFame(pop Population, fitness []float64) Population {
+	if pop.Cfg.HallOfFameSize <= 0 {
+		return pop
+	}
+	speciesIDs := make(map[int]int)
+	for _, species := range pop.Species {
+		for _, genomeIndex := range species.Genomes {
+			speciesIDs[genomeIndex] = species.ID
+		}
+	}
+
+	entries := make(map[uint64]HallOfFameEntry, len(pop.HallOfFame))
+	for _, entry := range pop.HallOfFame {
+		entries[entry.Fingerprint] = entry
+	}
+	for i, genome := range pop.Genomes {
+		fingerprint := genome.Fingerprint()
+		if existing, ok := entries[fingerprint]; ok && existing.Fitness >= fitness[i] {
+			continue
+		}
+		entries[fingerprint] = HallOfFameEntry{
+			Genome:      genome,
+			Fitness:     fitness[i],
+			Generation:  pop.Generation,
+			SpeciesID:   speciesIDs[i],
+			Fingerprint: fingerprint,
+		}
+	}
+
+	hallOfFame := make([]HallOfFameEntry, 0, len(entries))
+	for _, entry := range entries {
+		hallOfFame = append(hallOfFame, entry)
+	}
+	sort.Slice(hallOfFame, func(i, j int) bool {
+		if hallOfFame[i].Fitness != hallOfFame[j].Fitness {
+			return hallOfFame[i].Fitness > hallOfFame[j].Fitness
+		}
+		// Prefer the longest serving entry, and keep the order stable.
+		if hallOfFame[i].Generation != hallOfFame[j].Generation {
+			return hallOfFame[i].Generation < hallOfFame[j].Generation
+		}
+		return hallOfFame[i].Fingerprint < hallOfFame[j].Fingerprint
+	})
+	if len(hallOfFame) > pop.Cfg.HallOfFameSize {
+		hallOfFame = hallOfFame[:pop.Cfg.HallOfFameSize]
+	}
+	pop.HallOfFame = hallOfFame
+	return pop
+}
+
+// ActivateEnsemble activates each genome with the input, and returns the mean of their outputs.
+func ActivateEnsemble(genomes []Genome, input []float64) ([]float64, error) {
+	var mean []float64
+	for i, genome := range genomes {
+		output, err := network.Activate(genome.Layers.Nodes(), genome.Connections, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to activate genome %d: %w", i, err)
+		}
+		if mean == nil {
+			mean = make([]float64, len(output))
+		}
+		if len(output) != len(mean) {
+			return nil, fmt.Errorf("genome %d has %d outputs, expected %d", i, len(output), len(mean))
+		}
+		for j := range output {
+			mean[j] += output[j] / float64(len(genomes))
+		}
+	}
+	return mean, nil
+}
@@ -0,0 +1,91 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUpdateHallOfFame(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 4
+	cfg.HallOfFameSize = 3
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	// Two copies of the same genome should only be entered once.
+	pop.Genomes[1] = neat.CopyGenome(pop.Genomes[0])
+	pop.Generation = 1
+	pop = neat.Speciate(pop)
+
+	pop = neat.UpdateHallOfFame(pop, []float64{1, 4, 2, 3})
+	assert.Len(t, pop.HallOfFame, 3)
+	assert.Equal(t, []float64{4, 3, 2}, hallOfFameFitness(pop))
+	assert.Equal(t, pop.Genomes[0].Fingerprint(), pop.HallOfFame[0].Fingerprint)
+	for _, entry := range pop.HallOfFame {
+		assert.Equal(t, 1, entry.Generation)
+		assert.NotZero(t, entry.SpeciesID)
+	}
+
+	// Lower scores don't replace an existing entry, and higher scores do.
+	pop.Generation = 2
+	pop = neat.UpdateHallOfFame(pop, []float64{0, 0, 5, 0})
+	assert.Equal(t, []float64{5, 4, 3}, hallOfFameFitness(pop))
+	assert.Equal(t, 2, pop.HallOfFame[0].Generation)
+	assert.Equal(t, 1, pop.HallOfFame[1].Generation)
+	assert.Len(t, pop.HallOfFameGenomes(), 3)
+}
+
+func TestUpdateHallOfFame_Disabled(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 4
+	cfg.HallOfFameSize = 0
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	pop = neat.UpdateHallOfFame(pop, []float64{1, 2, 3, 4})
+	assert.Empty(t, pop.HallOfFame)
+}
+
+func TestRunGeneration_HallOfFame(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 20
+	cfg.HallOfFameSize = 5
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		pop, err = runGenerationWithFitness(pop, func(i int) float64 {
+			return float64(i)
+		})
+		assert.NoError(t, err)
+	}
+	assert.Len(t, pop.HallOfFame, 5)
+	assert.Equal(t, pop.BestEverGenomeFitness, pop.HallOfFame[0].Fitness)
+	seen := make(map[uint64]bool)
+	for _, entry := range pop.HallOfFame {
+		assert.False(t, seen[entry.Fingerprint], "duplicate entry in hall of fame")
+		seen[entry.Fingerprint] = true
+	}
+}
+
+func TestActivateEnsemble(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	a, err := neat.GenerateGenome(cfg)
+	assert.NoError(t, err)
+	b, err := neat.GenerateGenome(cfg)
+	assert.NoError(t, err)
+
+	outputA, err := neat.ActivateEnsemble([]neat.Genome{a}, []float64{1, 0})
+	assert.NoError(t, err)
+	outputB, err := neat.ActivateEnsemble([]neat.Genome{b}, []float64{1, 0})
+	assert.NoError(t, err)
+	output, err := neat.ActivateEnsemble([]neat.Genome{a, b}, []float64{1, 0})
+	assert.NoError(t, err)
+	assert.InDelta(t, (outputA[0]+outputB[0])/2, output[0], 1e-9)
+}
+
+func hallOfFameFitness(pop neat.Population) []float64 {
+	fitness := make([]float64, len(pop.HallOfFame))
+	for i, entry := range pop.HallOfFame {
+		fitness[i] = entry.Fitness
+	}
+	return fitness
+}
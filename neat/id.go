@@ -31,3 +31,25 @@ func (p *SequentialIDProvider) SetCurrent(n int) {
 	defer p.mu.Unlock()
 	p.current = n
 }
+
+// advanceIDProvider makes sure the provider won't hand out any innovation ID already used by the genomes, so that
+// genomes loaded from elsewhere can be mutated safely.
+func advanceIDProvider(provider IDProvider, genomes ...Genome) {
+	maxID := 0
+	for _, genome := range genomes {
+		for _, node := range genome.Layers.Nodes() {
+			if node.ID > maxID {
+				maxID = node.ID
+			}
+		}
+		for _, connection := range genome.Connections {
+			if connection.ID > maxID {
+				maxID = connection.ID
+			}
+		}
+	}
+	// IDProvider can't report its current ID, so take one to find out. Skipping an ID is harmless.
+	if provider.Next() <= maxID {
+		provider.SetCurrent(maxID)
+	}
+}
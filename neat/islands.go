@@ -0,0 +1,228 @@
+package neat
+
+import (
+	"fmt"
+	"github.com/jmwri/neatgo/util"
+	"sync"
+)
+
+type MigrationTopology string
+
+const (
+	// RingTopology sends migrants from each island to the next one.
+	RingTopology MigrationTopology = "ring"
+	// FullTopology sends migrants from each island to every other island.
+	FullTopology MigrationTopology = "full"
+	// RandomTopology sends migrants from each island to one other island, chosen at random each migration.
+	RandomTopology MigrationTopology = "random"
+)
+
+type IslandConfig struct {
+	MigrationInterval int               // How many generations between migrations. 0 disables migration.
+	MigrationSize     int               // How many of the best genomes each island sends on each migration.
+	Topology          MigrationTopology // Which islands receive migrants from each island.
+}
+
+func DefaultIslandConfig() IslandConfig {
+	return IslandConfig{
+		MigrationInterval: 10,
+		MigrationSize:     2,
+		Topology:          RingTopology,
+	}
+}
+
+// Islands is a set of populations evolved side by side, which occasionally exchange their best genomes.
+type Islands struct {
+	Cfg         IslandConfig
+	Populations []Population
+	Generation  int
+}
+
+// GenerateIslands generates a population for each config. Every island uses the IDProvider of the first config, so
+// that migrants share innovation IDs with the genomes on the island they join.
+func GenerateIslands(cfg IslandConfig, configs ...Config) (Islands, error) {
+	islands := Islands{
+		Cfg:         cfg,
+		Populations: make([]Population, len(configs)),
+	}
+	if len(configs) == 0 {
+		return islands, fmt.Errorf("at least one island is required")
+	}
+	for i, islandCfg := range configs {
+		if !equalLayers(islandCfg.Layers, configs[0].Layers) {
+			return islands, fmt.Errorf("island %d has layers %v, island 0 has %v", i, islandCfg.Layers, configs[0].Layers)
+		}
+		islandCfg.IDProvider = configs[0].IDProvider
+		pop, err := GeneratePopulation(islandCfg)
+		if err != nil {
+			return islands, fmt.Errorf("failed to generate island %d: %w", i, err)
+		}
+		islands.Populations[i] = pop
+	}
+	return islands, nil
+}
+
+// RunIslandGeneration evaluates every island concurrently with evaluator, exchanges migrants if it is time to, and
+// then breeds the next generation of every island.
+func RunIslandGeneration(islands Islands, evaluator Evaluator) (Islands, error) {
+	islands.Generation++
+	err := forEachIsland(islands, func(pop Population) (Population, error) {
+		for _, state := range pop.States() {
+			go evaluator(state)
+		}
+		return evaluateGenomes(pop), nil
+	})
+	if err != nil {
+		return islands, err
+	}
+
+	if islands.Cfg.MigrationInterval > 0 && islands.Generation%islands.Cfg.MigrationInterval == 0 {
+		islands = Migrate(islands)
+	}
+
+	err = forEachIsland(islands, NextGeneration)
+	return islands, err
+}
+
+// forEachIsland replaces every population with the result of fn, running fn on each island concurrently.
+func forEachIsland(islands Islands, fn func(pop Population) (Population, error)) error {
+	errs := make([]error, len(islands.Populations))
+	wg := sync.WaitGroup{}
+	wg.Add(len(islands.Populations))
+	for i := range islands.Populations {
+		go func(i int) {
+			defer wg.Done()
+			islands.Populations[i], errs[i] = fn(islands.Populations[i])
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("island %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Migrate copies the best evaluated genomes from each island to its destinations in Cfg.Topology, where they replace
+// the worst genomes. Migrants keep their fitness, objectives, behaviour and case errors, and genomes already on an island aren't
+// sent to it again. At most half of an island's genomes are replaced.
+func Migrate(islands Islands) Islands {
+	numIslands := len(islands.Populations)
+	if numIslands < 2 || islands.Cfg.MigrationSize <= 0 {
+		return islands
+	}
+
+	// Choose and copy every island's migrants before any island changes, as a destination may replace genomes that
+	// its own migrants were chosen from.
+	migrants := make([][]migrant, numIslands)
+	for i, pop := range islands.Populations {
+		best := sortByFitness(pop, genomeIndexes(pop))
+		if len(best) > islands.Cfg.MigrationSize {
+			best = best[:islands.Cfg.MigrationSize]
+		}
+		for _, genomeIndex := range best {
+			migrants[i] = append(migrants[i], newMigrant(pop, genomeIndex))
+		}
+	}
+	sources := make([][]int, numIslands)
+	for i := range islands.Populations {
+		for _, destination := range migrationDestinations(islands.Cfg.Topology, i, numIslands) {
+			sources[destination] = append(sources[destination], i)
+		}
+	}
+
+	for destination, islandSources := range sources {
+		pop := islands.Populations[destination]
+		worst := sortByFitness(pop, genomeIndexes(pop))
+		maxReplaced := len(worst) / 2
+		worst = worst[len(worst)-maxReplaced:]
+
+		present := make(map[uint64]bool)
+		for _, genome := range pop.Genomes {
+			present[genome.Fingerprint()] = true
+		}
+		replaced := 0
+		for _, source := range islandSources {
+			for _, m := range migrants[source] {
+				if replaced >= maxReplaced || present[m.fingerprint] {
+					continue
+				}
+				present[m.fingerprint] = true
+				// Replace the worst remaining genome, which is last.
+				target := worst[len(worst)-1-replaced]
+				pop.Genomes[target] = CopyGenome(m.genome)
+				pop.GenomeFitness[target] = m.fitness
+				pop.GenomeObjectives[target] = append([]float64(nil), m.objectives...)
+				pop.GenomeBehaviours[target] = append([]float64(nil), m.behaviour...)
+				pop.GenomeCaseErrors[target] = append([]float64(nil), m.caseErrors...)
+				replaced++
+			}
+		}
+	}
+	return islands
+}
+
+// migrant is a copy of a genome and its evaluation, taken before migration changes the island it came from.
+type migrant struct {
+	genome      Genome
+	fingerprint uint64
+	fitness     float64
+	objectives  []float64
+	behaviour   []float64
+	caseErrors  []float64
+}
+
+func newMigrant(pop Population, genomeIndex int) migrant {
+	return migrant{
+		genome:      CopyGenome(pop.Genomes[genomeIndex]),
+		fingerprint: pop.Genomes[genomeIndex].Fingerprint(),
+		fitness:     pop.GenomeFitness[genomeIndex],
+		objectives:  append([]float64(nil), pop.GenomeObjectives[genomeIndex]...),
+		behaviour:   append([]float64(nil), pop.GenomeBehaviours[genomeIndex]...),
+		caseErrors:  append([]float64(nil), pop.GenomeCaseErrors[genomeIndex]...),
+	}
+}
+
+// migrationDestinations returns the islands that receive migrants from island i.
+func migrationDestinations(topology MigrationTopology, i int, numIslands int) []int {
+	switch topology {
+	case FullTopology:
+		destinations := make([]int, 0, numIslands-1)
+		for j := 0; j < numIslands; j++ {
+			if j != i {
+				destinations = append(destinations, j)
+			}
+		}
+		return destinations
+	case RandomTopology:
+		// Pick from every other island.
+		j := util.IntBetween(0, numIslands-2)
+		if j >= i {
+			j++
+		}
+		return []int{j}
+	default:
+		return []int{(i + 1) % numIslands}
+	}
+}
+
+func genomeIndexes(pop Population) []int {
+	indexes := make([]int, len(pop.Genomes))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	return indexes
+}
+
+func equalLayers(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
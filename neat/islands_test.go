@@ -0,0 +1,181 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func islandConfigs(n int) []neat.Config {
+	configs := make([]neat.Config, n)
+	for i := range configs {
+		configs[i] = neat.DefaultConfig(2, 1)
+		configs[i].PopulationSize = 10
+	}
+	return configs
+}
+
+func TestGenerateIslands(t *testing.T) {
+	islands, err := neat.GenerateIslands(neat.DefaultIslandConfig(), islandConfigs(3)...)
+	assert.NoError(t, err)
+	assert.Len(t, islands.Populations, 3)
+
+	// Every island draws from the same innovation IDs.
+	seen := make(map[int]bool)
+	for _, pop := range islands.Populations {
+		assert.Same(t, islands.Populations[0].Cfg.IDProvider, pop.Cfg.IDProvider)
+		for _, genome := range pop.Genomes {
+			for _, node := range genome.Layers.Nodes() {
+				assert.False(t, seen[node.ID], "node ID %d used twice", node.ID)
+				seen[node.ID] = true
+			}
+		}
+	}
+
+	configs := islandConfigs(2)
+	configs[1].Layers = []int{3, 1}
+	_, err = neat.GenerateIslands(neat.DefaultIslandConfig(), configs...)
+	assert.Error(t, err)
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		topology      neat.MigrationTopology
+		expectedFroms [][]int
+	}{
+		{neat.RingTopology, [][]int{{2}, {0}, {1}}},
+		{neat.FullTopology, [][]int{{1, 2}, {0, 2}, {0, 1}}},
+	}
+	for _, test := range tests {
+		t.Run(string(test.topology), func(t *testing.T) {
+			islands, err := neat.GenerateIslands(neat.IslandConfig{
+				MigrationSize: 2,
+				Topology:      test.topology,
+			}, islandConfigs(3)...)
+			assert.NoError(t, err)
+			for i, pop := range islands.Populations {
+				for j := range pop.GenomeFitness {
+					pop.GenomeFitness[j] = float64(i*100 + j)
+				}
+			}
+			best := make([][]uint64, 3)
+			for i, pop := range islands.Populations {
+				best[i] = []uint64{pop.Genomes[9].Fingerprint(), pop.Genomes[8].Fingerprint()}
+			}
+
+			islands = neat.Migrate(islands)
+			for i, pop := range islands.Populations {
+				fingerprints := make(map[uint64]bool)
+				for _, genome := range pop.Genomes {
+					fingerprints[genome.Fingerprint()] = true
+				}
+				for _, from := range test.expectedFroms[i] {
+					assert.True(t, fingerprints[best[from][0]], "island %d missing best migrant from %d", i, from)
+					assert.True(t, fingerprints[best[from][1]], "island %d missing second migrant from %d", i, from)
+				}
+				// The island's own best genomes are kept, and the worst are replaced.
+				assert.True(t, fingerprints[best[i][0]])
+				assert.True(t, fingerprints[best[i][1]])
+				assert.NotEqual(t, float64(i*100), pop.GenomeFitness[0])
+				assert.Len(t, pop.Genomes, 10)
+			}
+		})
+	}
+}
+
+func TestMigrate_LargeMigration(t *testing.T) {
+	islands, err := neat.GenerateIslands(neat.IslandConfig{
+		MigrationSize: 8,
+		Topology:      neat.RingTopology,
+	}, islandConfigs(3)...)
+	assert.NoError(t, err)
+	for i, pop := range islands.Populations {
+		for j := range pop.GenomeFitness {
+			pop.GenomeFitness[j] = float64(i*100 + j)
+		}
+	}
+	// Island 1 already has island 0's best genomes, so it takes island 0's weaker migrants, which island 0 replaces
+	// with migrants from island 2.
+	for j := 5; j < 10; j++ {
+		islands.Populations[1].Genomes[j] = neat.CopyGenome(islands.Populations[0].Genomes[j])
+	}
+	islandZero := make(map[uint64]float64)
+	for j, genome := range islands.Populations[0].Genomes {
+		islandZero[genome.Fingerprint()] = islands.Populations[0].GenomeFitness[j]
+	}
+
+	islands = neat.Migrate(islands)
+	migrants := 0
+	for j, genome := range islands.Populations[1].Genomes {
+		fitness := islands.Populations[1].GenomeFitness[j]
+		if fitness >= 100 {
+			assert.Less(t, fitness, 200.)
+			continue
+		}
+		migrants++
+		expected, ok := islandZero[genome.Fingerprint()]
+		assert.True(t, ok, "genome %d didn't come from island 0", j)
+		assert.Equal(t, expected, fitness)
+	}
+	assert.Equal(t, 3, migrants)
+}
+
+func TestMigrate_Random(t *testing.T) {
+	islands, err := neat.GenerateIslands(neat.IslandConfig{
+		MigrationSize: 1,
+		Topology:      neat.RandomTopology,
+	}, islandConfigs(4)...)
+	assert.NoError(t, err)
+	for i, pop := range islands.Populations {
+		for j := range pop.GenomeFitness {
+			pop.GenomeFitness[j] = float64(i*100 + j)
+		}
+	}
+	islands = neat.Migrate(islands)
+
+	migrants := 0
+	for i, pop := range islands.Populations {
+		for _, fitness := range pop.GenomeFitness {
+			if int(fitness)/100 != i {
+				migrants++
+			}
+		}
+	}
+	assert.Equal(t, 4, migrants)
+}
+
+func TestRunIslandGeneration(t *testing.T) {
+	islands, err := neat.GenerateIslands(neat.IslandConfig{
+		MigrationInterval: 2,
+		MigrationSize:     2,
+		Topology:          neat.RingTopology,
+	}, islandConfigs(3)...)
+	assert.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		islands, err = neat.RunIslandGeneration(islands, xorEvaluator)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 4, islands.Generation)
+	for _, pop := range islands.Populations {
+		assert.Equal(t, 4, pop.Generation)
+		assert.Len(t, pop.Genomes, 10)
+		assertSpeciesCoverPopulation(t, pop)
+	}
+}
+
+// xorEvaluator scores a genome by how close it gets to XOR.
+func xorEvaluator(state neat.ClientGenomeState) {
+	fitness := 4.0
+	for _, input := range [][]float64{{0, 0}, {0, 1}, {1, 0}, {1, 1}} {
+		state.SendInput() <- input
+		output := <-state.GetOutput()
+		expected := 0.0
+		if input[0] != input[1] {
+			expected = 1
+		}
+		fitness -= (output[0] - expected) * (output[0] - expected)
+	}
+	close(state.SendInput())
+	state.SendFitness() <- fitness
+}
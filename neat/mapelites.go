@@ -0,0 +1,182 @@
+package neat
+
+import (
+	"encoding/gob"
+	"fmt"
+	"github.com/jmwri/neatgo/util"
+	"io"
+	"math"
+)
+
+// BehaviourDimension is one axis of a MAP-Elites grid. Descriptor values between Min and Max are split evenly into
+// Bins cells, and values outside the range are placed in the nearest cell.
+type BehaviourDimension struct {
+	Min, Max float64
+	Bins     int
+}
+
+// Elite is the best genome found for a cell of a MAP-Elites archive.
+type Elite struct {
+	Genome     Genome
+	Fitness    float64
+	Descriptor []float64
+}
+
+func NewMapElitesArchive(dimensions ...BehaviourDimension) *MapElitesArchive {
+	return &MapElitesArchive{
+		Dimensions: dimensions,
+		Elites:     make(map[int]Elite),
+	}
+}
+
+// MapElitesArchive is a grid over behaviour descriptors, where each cell keeps the fittest genome with a behaviour
+// in that cell.
+type MapElitesArchive struct {
+	Dimensions []BehaviourDimension
+	// Elites contains the elite for each filled cell, keyed by cell index.
+	Elites map[int]Elite
+	// Evaluations counts every genome offered to the archive.
+	Evaluations int
+}
+
+type MapElitesStatistics struct {
+	Cells       int
+	FilledCells int
+	// Coverage is the fraction of cells that are filled.
+	Coverage float64
+	// QDScore is the sum of the fitness of every elite.
+	QDScore     float64
+	BestFitness float64
+	AvgFitness  float64
+}
+
+// NumCells returns the total number of cells in the grid.
+func (a *MapElitesArchive) NumCells() int {
+	cells := 1
+	for _, dimension := range a.Dimensions {
+		cells *= dimension.Bins
+	}
+	return cells
+}
+
+// Cell returns the index of the cell that the descriptor falls in.
+func (a *MapElitesArchive) Cell(descriptor []float64) (int, error) {
+	if len(descriptor) != len(a.Dimensions) {
+		return 0, fmt.Errorf("descriptor has %d values, archive has %d dimensions", len(descriptor), len(a.Dimensions))
+	}
+	cell := 0
+	for i, dimension := range a.Dimensions {
+		if dimension.Bins < 1 || dimension.Max <= dimension.Min {
+			return 0, fmt.Errorf("dimension %d is invalid", i)
+		}
+		position := (descriptor[i] - dimension.Min) / (dimension.Max - dimension.Min)
+		// Clamp before converting, as converting an infinite float to int is undefined. NaN goes in the first bin.
+		bin := 0
+		if position >= 1 {
+			bin = dimension.Bins - 1
+		} else if position > 0 {
+			bin = int(math.Floor(position * float64(dimension.Bins)))
+		}
+		cell = cell*dimension.Bins + bin
+	}
+	return cell, nil
+}
+
+// Add offers an evaluated genome to the archive. It becomes the elite of its cell if the cell is empty or it is
+// fitter than the current elite, in which case true is returned. Genomes with NaN fitness are never added.
+func (a *MapElitesArchive) Add(genome Genome, fitness float64, descriptor []float64) (bool, error) {
+	cell, err := a.Cell(descriptor)
+	if err != nil {
+		return false, err
+	}
+	a.Evaluations++
+	if math.IsNaN(fitness) {
+		return false, nil
+	}
+	if elite, ok := a.Elites[cell]; ok && elite.Fitness >= fitness {
+		return false, nil
+	}
+	a.Elites[cell] = Elite{
+		Genome:     genome,
+		Fitness:    fitness,
+		Descriptor: descriptor,
+	}
+	return true, nil
+}
+
+// Offspring breeds n new genomes from parents chosen uniformly from the archive, using Crossover with
+// cfg.MateCrossoverRate and then MutateGenome. If the archive is empty, new genomes are generated.
+func (a *MapElitesArchive) Offspring(cfg Config, n int) ([]Genome, error) {
+	offspring := make([]Genome, n)
+	elites := make([]int, 0, len(a.Elites))
+	for cell := range a.Elites {
+		elites = append(elites, cell)
+	}
+	for i := range offspring {
+		if len(elites) == 0 {
+			genome, err := GenerateGenome(cfg)
+			if err != nil {
+				return offspring, fmt.Errorf("failed to generate genome: %w", err)
+			}
+			offspring[i] = genome
+			continue
+		}
+		parent := a.Elites[util.RandSliceElement(elites)]
+		baby := CopyGenome(parent.Genome)
+		if util.FloatBetween(0, 1) < cfg.MateCrossoverRate {
+			other := a.Elites[util.RandSliceElement(elites)]
+			if other.Fitness > parent.Fitness {
+				parent, other = other, parent
+			}
+			baby = Crossover(cfg, parent.Genome, other.Genome)
+		}
+		offspring[i] = MutateGenome(cfg, baby)
+	}
+	return offspring, nil
+}
+
+func (a *MapElitesArchive) Statistics() MapElitesStatistics {
+	stats := MapElitesStatistics{
+		Cells:       a.NumCells(),
+		FilledCells: len(a.Elites),
+		BestFitness: math.Inf(-1),
+	}
+	for _, elite := range a.Elites {
+		stats.QDScore += elite.Fitness
+		stats.BestFitness = math.Max(stats.BestFitness, elite.Fitness)
+	}
+	if stats.Cells > 0 {
+		stats.Coverage = float64(stats.FilledCells) / float64(stats.Cells)
+	}
+	if stats.FilledCells > 0 {
+		stats.AvgFitness = stats.QDScore / float64(stats.FilledCells)
+	}
+	return stats
+}
+
+// WriteMapElitesArchive saves the archive so it can be loaded with ReadMapElitesArchive.
+// Archives are gob encoded, like checkpoints, because fitness and descriptors may be infinite.
+func WriteMapElitesArchive(w io.Writer, archive *MapElitesArchive) error {
+	if err := gob.NewEncoder(w).Encode(archive); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// ReadMapElitesArchive loads an archive saved with WriteMapElitesArchive.
+// cfg.IDProvider is advanced past every innovation ID in the archive, so that offspring get new innovation IDs.
+func ReadMapElitesArchive(r io.Reader, cfg Config) (*MapElitesArchive, error) {
+	archive := NewMapElitesArchive()
+	if err := gob.NewDecoder(r).Decode(archive); err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	if archive.Elites == nil {
+		archive.Elites = make(map[int]Elite)
+	}
+	genomes := make([]Genome, 0, len(archive.Elites))
+	for _, elite := range archive.Elites {
+		genomes = append(genomes, elite.Genome)
+	}
+	advanceIDProvider(cfg.IDProvider, genomes...)
+	return archive, nil
+}
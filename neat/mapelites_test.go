@@ -0,0 +1,140 @@
+package neat_test
+
+import (
+	"bytes"
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestMapElitesArchive_Cell(t *testing.T) {
+	archive := neat.NewMapElitesArchive(
+		neat.BehaviourDimension{Min: 0, Max: 1, Bins: 4},
+		neat.BehaviourDimension{Min: -1, Max: 1, Bins: 2},
+	)
+	assert.Equal(t, 8, archive.NumCells())
+
+	tests := []struct {
+		descriptor []float64
+		expected   int
+	}{
+		{[]float64{0, -1}, 0},
+		{[]float64{0, 1}, 1},
+		{[]float64{.3, -.5}, 2},
+		{[]float64{1, 1}, 7},
+		// Out of range values go to the nearest cell.
+		{[]float64{-5, 5}, 1},
+		{[]float64{5, -5}, 6},
+		{[]float64{math.Inf(1), math.Inf(-1)}, 6},
+		{[]float64{math.NaN(), math.Inf(1)}, 1},
+	}
+	for _, test := range tests {
+		cell, err := archive.Cell(test.descriptor)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, cell, "descriptor %v", test.descriptor)
+	}
+
+	_, err := archive.Cell([]float64{0})
+	assert.Error(t, err)
+}
+
+func TestMapElitesArchive_Add(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	archive := neat.NewMapElitesArchive(neat.BehaviourDimension{Min: 0, Max: 1, Bins: 10})
+	genome, err := neat.GenerateGenome(cfg)
+	assert.NoError(t, err)
+
+	added, err := archive.Add(genome, 1, []float64{.55})
+	assert.NoError(t, err)
+	assert.True(t, added)
+	added, err = archive.Add(genome, .5, []float64{.51})
+	assert.NoError(t, err)
+	assert.False(t, added, "worse genome should not replace elite")
+	added, err = archive.Add(genome, 2, []float64{.59})
+	assert.NoError(t, err)
+	assert.True(t, added)
+	added, err = archive.Add(genome, 3, []float64{.05})
+	assert.NoError(t, err)
+	assert.True(t, added)
+	added, err = archive.Add(genome, math.NaN(), []float64{.05})
+	assert.NoError(t, err)
+	assert.False(t, added, "NaN fitness should not replace elite")
+
+	stats := archive.Statistics()
+	assert.Equal(t, 10, stats.Cells)
+	assert.Equal(t, 2, stats.FilledCells)
+	assert.InDelta(t, .2, stats.Coverage, 1e-9)
+	assert.InDelta(t, 5, stats.QDScore, 1e-9)
+	assert.Equal(t, 3., stats.BestFitness)
+	assert.InDelta(t, 2.5, stats.AvgFitness, 1e-9)
+	assert.Equal(t, 5, archive.Evaluations)
+}
+
+func TestMapElitesArchive_Offspring(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	archive := neat.NewMapElitesArchive(
+		neat.BehaviourDimension{Min: 0, Max: 1, Bins: 5},
+		neat.BehaviourDimension{Min: 0, Max: 1, Bins: 5},
+	)
+
+	for i := 0; i < 10; i++ {
+		genomes, err := archive.Offspring(cfg, 20)
+		assert.NoError(t, err)
+		assert.Len(t, genomes, 20)
+		for _, genome := range genomes {
+			assert.Empty(t, neat.ValidateGenome(cfg, genome))
+			fitness, descriptor := mapElitesBehaviour(genome)
+			_, err := archive.Add(genome, fitness, descriptor)
+			assert.NoError(t, err)
+		}
+	}
+	assert.Greater(t, archive.Statistics().FilledCells, 1)
+}
+
+func TestWriteMapElitesArchive(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	archive := neat.NewMapElitesArchive(neat.BehaviourDimension{Min: 0, Max: 1, Bins: 5})
+	genomes, err := archive.Offspring(cfg, 10)
+	assert.NoError(t, err)
+	for _, genome := range genomes {
+		fitness, descriptor := mapElitesBehaviour(genome)
+		_, err := archive.Add(genome, fitness, descriptor[:1])
+		assert.NoError(t, err)
+	}
+
+	_, err = archive.Add(genomes[0], math.Inf(1), []float64{math.Inf(-1)})
+	assert.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	assert.NoError(t, neat.WriteMapElitesArchive(&buf, archive))
+
+	loadCfg := neat.DefaultConfig(2, 1)
+	loaded, err := neat.ReadMapElitesArchive(&buf, loadCfg)
+	assert.NoError(t, err)
+	assert.Equal(t, archive.Statistics(), loaded.Statistics())
+	assert.Equal(t, archive.Evaluations, loaded.Evaluations)
+	for cell, elite := range archive.Elites {
+		assert.Equal(t, elite.Genome.Fingerprint(), loaded.Elites[cell].Genome.Fingerprint())
+	}
+
+	// New genomes must not reuse innovation IDs from the loaded archive.
+	fresh, err := neat.GenerateGenome(loadCfg)
+	assert.NoError(t, err)
+	freshIDs := make(map[int]bool)
+	for _, node := range fresh.Layers.Nodes() {
+		freshIDs[node.ID] = true
+	}
+	for _, elite := range loaded.Elites {
+		for _, node := range elite.Genome.Layers.Nodes() {
+			assert.False(t, freshIDs[node.ID], "node %d reused", node.ID)
+		}
+	}
+}
+
+// mapElitesBehaviour describes a genome by its shape, which is cheap and varies as genomes are mutated.
+func mapElitesBehaviour(genome neat.Genome) (float64, []float64) {
+	nodes := float64(genome.NumNodes()) / 10
+	connections := float64(genome.NumConnections()) / 20
+	return nodes + connections, []float64{nodes, connections}
+}
@@ -0,0 +1,162 @@
+package neat
+
+import (
+	"math"
+	"sort"
+)
+
+// ParetoSolution is a genome on the Pareto front, along with the objectives it achieved.
+type ParetoSolution struct {
+	Genome     Genome
+	Objectives []float64
+}
+
+// RankObjectives replaces the fitness of each genome with a score from NSGA-II non-dominated sorting of its objectives,
+// where every objective is maximised. Genomes in better fronts always score higher, and within a front genomes that
+// are less crowded score higher, so the rest of speciation and selection can treat the score as fitness.
+// Genomes with missing objectives are placed after every front, all with the same score.
+func RankObjectives(pop Population) Population {
+	numObjectives := 0
+	for _, objectives := range pop.GenomeObjectives {
+		if len(objectives) > numObjectives {
+			numObjectives = len(objectives)
+		}
+	}
+	complete := make([]int, 0, len(pop.GenomeObjectives))
+	incomplete := make([]int, 0)
+	completeObjectives := make([][]float64, 0, len(pop.GenomeObjectives))
+	for i, objectives := range pop.GenomeObjectives {
+		if len(objectives) == numObjectives && numObjectives > 0 {
+			complete = append(complete, i)
+			completeObjectives = append(completeObjectives, objectives)
+		} else {
+			incomplete = append(incomplete, i)
+		}
+	}
+
+	fronts := NonDominatedSort(completeObjectives)
+	for i, front := range fronts {
+		for j := range front {
+			front[j] = complete[front[j]]
+		}
+		fronts[i] = front
+	}
+	if len(incomplete) > 0 {
+		fronts = append(fronts, incomplete)
+	}
+
+	pop.ParetoFront = make([]ParetoSolution, 0)
+	for frontIndex, front := range fronts {
+		frontObjectives := make([][]float64, len(front))
+		for i, genomeIndex := range front {
+			frontObjectives[i] = pop.GenomeObjectives[genomeIndex]
+		}
+		crowding := make([]float64, len(front))
+		if frontIndex < len(fronts)-1 || len(incomplete) == 0 {
+			crowding = CrowdingDistance(frontObjectives)
+		}
+		for i, genomeIndex := range front {
+			// Map crowding distance to [0, 1) so it only orders genomes within a front.
+			crowdingScore := crowding[i] / (1 + crowding[i])
+			if math.IsInf(crowding[i], 1) {
+				crowdingScore = math.Nextafter(1, 0)
+			}
+			pop.GenomeFitness[genomeIndex] = float64(len(fronts)-1-frontIndex) + crowdingScore
+			if frontIndex == 0 && len(complete) > 0 {
+				pop.ParetoFront = append(pop.ParetoFront, ParetoSolution{
+					Genome:     pop.Genomes[genomeIndex],
+					Objectives: pop.GenomeObjectives[genomeIndex],
+				})
+			}
+		}
+	}
+	return pop
+}
+
+// NonDominatedSort groups solutions into Pareto fronts, where every objective is maximised. The first front contains
+// the indices of solutions not dominated by any other, the second those only dominated by the first, and so on.
+func NonDominatedSort(objectives [][]float64) [][]int {
+	dominatedBy := make([]int, len(objectives))
+	dominates := make([][]int, len(objectives))
+	fronts := make([][]int, 0)
+	current := make([]int, 0)
+	for i := range objectives {
+		for j := range objectives {
+			if i == j {
+				continue
+			}
+			if paretoDominates(objectives[i], objectives[j]) {
+				dominates[i] = append(dominates[i], j)
+			} else if paretoDominates(objectives[j], objectives[i]) {
+				dominatedBy[i]++
+			}
+		}
+		if dominatedBy[i] == 0 {
+			current = append(current, i)
+		}
+	}
+	for len(current) > 0 {
+		fronts = append(fronts, current)
+		next := make([]int, 0)
+		for _, i := range current {
+			for _, j := range dominates[i] {
+				dominatedBy[j]--
+				if dominatedBy[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		current = next
+	}
+	return fronts
+}
+
+// CrowdingDistance returns how isolated each solution in a front is from its neighbours in objective space.
+// Solutions at the extremes of any objective have an infinite distance. If solutions have different numbers of
+// objectives, only the objectives they all have are compared.
+func CrowdingDistance(objectives [][]float64) []float64 {
+	distances := make([]float64, len(objectives))
+	if len(objectives) == 0 {
+		return distances
+	}
+	numObjectives := len(objectives[0])
+	for _, solution := range objectives {
+		if len(solution) < numObjectives {
+			numObjectives = len(solution)
+		}
+	}
+	order := make([]int, len(objectives))
+	for m := 0; m < numObjectives; m++ {
+		for i := range order {
+			order[i] = i
+		}
+		sort.SliceStable(order, func(i, j int) bool {
+			return objectives[order[i]][m] < objectives[order[j]][m]
+		})
+		minValue := objectives[order[0]][m]
+		maxValue := objectives[order[len(order)-1]][m]
+		distances[order[0]] = math.Inf(1)
+		distances[order[len(order)-1]] = math.Inf(1)
+		if maxValue == minValue {
+			continue
+		}
+		for i := 1; i < len(order)-1; i++ {
+			distances[order[i]] += (objectives[order[i+1]][m] - objectives[order[i-1]][m]) / (maxValue - minValue)
+		}
+	}
+	return distances
+}
+
+// paretoDominates reports whether a is at least as good as b in every objective, and better in at least one.
+func paretoDominates(a, b []float64) bool {
+	better := false
+	for i := range a {
+		if a[i] < b[i] {
+			return false
+		}
+		if a[i] > b[i] {
+			better = true
+		}
+	}
+	return better
+}
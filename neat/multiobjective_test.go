@@ -0,0 +1,132 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestNonDominatedSort(t *testing.T) {
+	objectives := [][]float64{
+		{1, 5},
+		{5, 1},
+		{3, 3},
+		{2, 2},
+		{1, 1},
+		{0, 0},
+	}
+	fronts := neat.NonDominatedSort(objectives)
+	assert.Equal(t, [][]int{{0, 1, 2}, {3}, {4}, {5}}, fronts)
+}
+
+func TestCrowdingDistance(t *testing.T) {
+	distances := neat.CrowdingDistance([][]float64{
+		{1, 5},
+		{5, 1},
+		{2, 4},
+	})
+	assert.True(t, math.IsInf(distances[0], 1))
+	assert.True(t, math.IsInf(distances[1], 1))
+	assert.Equal(t, 2.0, distances[2])
+
+	distances = neat.CrowdingDistance([][]float64{{1, 5}, {5}, nil})
+	assert.Equal(t, []float64{0, 0, 0}, distances)
+}
+
+func TestRankObjectives(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 5
+	cfg.MultiObjective = true
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	pop.GenomeObjectives = [][]float64{
+		{1, 5},
+		{2, 2},
+		{5, 1},
+		nil,
+		{2, 4},
+	}
+
+	pop = neat.RankObjectives(pop)
+	assert.Len(t, pop.ParetoFront, 3)
+	assert.Equal(t, []float64{1, 5}, pop.ParetoFront[0].Objectives)
+	// The less crowded ends of the front score higher than the middle, which scores higher than dominated genomes.
+	assert.Greater(t, pop.GenomeFitness[0], pop.GenomeFitness[4])
+	assert.Greater(t, pop.GenomeFitness[2], pop.GenomeFitness[4])
+	assert.Greater(t, pop.GenomeFitness[4], pop.GenomeFitness[1])
+	assert.Greater(t, pop.GenomeFitness[1], pop.GenomeFitness[3])
+}
+
+func TestRunGeneration_MultiObjective(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 10
+	cfg.MultiObjective = true
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	for i, state := range pop.States() {
+		go func(i int, state neat.ClientGenomeState) {
+			close(state.SendInput())
+			state.SendObjectives() <- []float64{float64(i), float64(-i)}
+			state.SendFitness() <- 0
+			close(state.SendFitness())
+		}(i, state)
+	}
+	pop, err = neat.RunGeneration(pop)
+	assert.NoError(t, err)
+	// No genome dominates another, so every genome is on the front.
+	assert.Len(t, pop.ParetoFront, cfg.PopulationSize)
+	assert.Len(t, pop.Genomes, cfg.PopulationSize)
+}
+
+func TestRunGeneration_MixedObjectiveLengths(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 3
+	cfg.MultiObjective = true
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	objectives := [][]float64{{1, 2}, {5}, nil}
+	for i, state := range pop.States() {
+		go func(i int, state neat.ClientGenomeState) {
+			close(state.SendInput())
+			if objectives[i] != nil {
+				state.SendObjectives() <- objectives[i]
+			}
+			state.SendFitness() <- 0
+			close(state.SendFitness())
+		}(i, state)
+	}
+	pop, err = neat.RunGeneration(pop)
+	assert.NoError(t, err)
+	assert.Len(t, pop.ParetoFront, 1)
+	assert.Equal(t, []float64{1, 2}, pop.ParetoFront[0].Objectives)
+	assert.Len(t, pop.Genomes, cfg.PopulationSize)
+}
+
+func TestRunGeneration_MultiObjectiveBestGenome(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 10
+	cfg.MultiObjective = true
+	cfg.HallOfFameSize = 1
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	best := pop.Genomes[9]
+
+	// The genome with the highest fitness is on the last front.
+	for i, state := range pop.States() {
+		go func(i int, state neat.ClientGenomeState) {
+			close(state.SendInput())
+			state.SendObjectives() <- []float64{float64(-i)}
+			state.SendFitness() <- float64(i)
+			close(state.SendFitness())
+		}(i, state)
+	}
+	pop, err = neat.RunGeneration(pop)
+	assert.NoError(t, err)
+	assert.Equal(t, 9.0, pop.BestGenomeFitness)
+	assert.Equal(t, best.Fingerprint(), pop.BestEverGenome.Fingerprint())
+	assert.Len(t, pop.HallOfFame, 1)
+	assert.Equal(t, best.Fingerprint(), pop.HallOfFame[0].Fingerprint)
+}
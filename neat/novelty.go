@@ -0,0 +1,85 @@
+package neat
+
+import (
+	"github.com/jmwri/neatgo/util"
+	"math"
+	"sort"
+)
+
+// ScoreNovelty replaces the fitness of each genome with a score based on the novelty of its behaviour.
+// Novelty is the average distance to the Cfg.NoveltyNeighbours nearest behaviours in the population and the novelty
+// archive. Novelty and fitness are both scaled to [0, 1] and combined using Cfg.NoveltyFitnessWeight.
+// Novel behaviours are then added to the archive.
+func ScoreNovelty(pop Population) Population {
+	pop.GenomeNovelty = make([]float64, len(pop.Genomes))
+	for i, behaviour := range pop.GenomeBehaviours {
+		if behaviour == nil {
+			continue
+		}
+		distances := make([]float64, 0, len(pop.GenomeBehaviours)+len(pop.NoveltyArchive))
+		for j, other := range pop.GenomeBehaviours {
+			if i == j || other == nil {
+				continue
+			}
+			distances = append(distances, behaviourDistance(behaviour, other))
+		}
+		for _, other := range pop.NoveltyArchive {
+			distances = append(distances, behaviourDistance(behaviour, other))
+		}
+		pop.GenomeNovelty[i] = averageNearest(distances, pop.Cfg.NoveltyNeighbours)
+	}
+
+	weight := math.Max(0, math.Min(1, pop.Cfg.NoveltyFitnessWeight))
+	scaledFitness := MinMaxFitness(pop.GenomeFitness)
+	scaledNovelty := MinMaxFitness(pop.GenomeNovelty)
+	for i := range pop.GenomeFitness {
+		pop.GenomeFitness[i] = weight*scaledFitness[i] + (1-weight)*scaledNovelty[i]
+	}
+
+	for i, behaviour := range pop.GenomeBehaviours {
+		if behaviour == nil {
+			continue
+		}
+		novel := pop.GenomeNovelty[i] >= pop.Cfg.NoveltyArchiveThreshold
+		lucky := util.FloatBetween(0, 1) < pop.Cfg.NoveltyArchiveAddProbability
+		if novel || lucky {
+			pop.NoveltyArchive = append(pop.NoveltyArchive, behaviour)
+		}
+	}
+	if maxSize := pop.Cfg.NoveltyArchiveMaxSize; maxSize > 0 && len(pop.NoveltyArchive) > maxSize {
+		pop.NoveltyArchive = pop.NoveltyArchive[len(pop.NoveltyArchive)-maxSize:]
+	}
+	return pop
+}
+
+// behaviourDistance is the euclidean distance between two behaviours. Missing dimensions count as 0.
+func behaviourDistance(a, b []float64) float64 {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	sumSquares := 0.0
+	for i := range a {
+		other := 0.0
+		if i < len(b) {
+			other = b[i]
+		}
+		sumSquares += math.Pow(a[i]-other, 2)
+	}
+	return math.Sqrt(sumSquares)
+}
+
+// averageNearest returns the average of the k smallest distances, or all of them if k is less than 1.
+func averageNearest(distances []float64, k int) float64 {
+	if len(distances) == 0 {
+		return 0
+	}
+	sort.Float64s(distances)
+	if k < 1 || k > len(distances) {
+		k = len(distances)
+	}
+	total := 0.0
+	for _, distance := range distances[:k] {
+		total += distance
+	}
+	return total / float64(k)
+}
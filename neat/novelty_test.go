@@ -0,0 +1,80 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func noveltyTestPopulation(t *testing.T) neat.Population {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 4
+	cfg.NoveltySearch = true
+	cfg.NoveltyNeighbours = 2
+	cfg.NoveltyArchiveThreshold = 5
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	pop.GenomeBehaviours = [][]float64{
+		{0, 0},
+		{0, 1},
+		{1, 0},
+		{10, 10},
+	}
+	pop.GenomeFitness = []float64{4, 3, 2, 1}
+	return pop
+}
+
+func TestScoreNovelty(t *testing.T) {
+	pop := noveltyTestPopulation(t)
+	pop = neat.ScoreNovelty(pop)
+
+	assert.Equal(t, 1.0, pop.GenomeNovelty[0])
+	// The outlier is the most novel, so with pure novelty it has the highest score despite the lowest fitness.
+	assert.Equal(t, 1.0, pop.GenomeFitness[3])
+	for i := 0; i < 3; i++ {
+		assert.Less(t, pop.GenomeFitness[i], pop.GenomeFitness[3])
+	}
+	// Only the outlier is novel enough for the archive.
+	assert.Equal(t, [][]float64{{10, 10}}, pop.NoveltyArchive)
+
+	// Once archived, the same behaviour is no longer novel.
+	pop.GenomeBehaviours = [][]float64{{0, 0}, {0, 1}, {1, 0}, {10, 10}}
+	pop = neat.ScoreNovelty(pop)
+	assert.Less(t, pop.GenomeNovelty[3], 10.0)
+}
+
+func TestScoreNovelty_Hybrid(t *testing.T) {
+	pop := noveltyTestPopulation(t)
+	pop.Cfg.NoveltyFitnessWeight = 1
+	pop = neat.ScoreNovelty(pop)
+	assert.Equal(t, []float64{1, 2.0 / 3, 1.0 / 3, 0}, pop.GenomeFitness)
+}
+
+func TestScoreNovelty_ArchiveMaxSize(t *testing.T) {
+	pop := noveltyTestPopulation(t)
+	pop.Cfg.NoveltyArchiveThreshold = 0
+	pop.Cfg.NoveltyArchiveMaxSize = 3
+	pop = neat.ScoreNovelty(pop)
+	assert.Equal(t, [][]float64{{0, 1}, {1, 0}, {10, 10}}, pop.NoveltyArchive)
+}
+
+func TestRunGeneration_NoveltySearch(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 10
+	cfg.NoveltySearch = true
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	for i, state := range pop.States() {
+		go func(i int, state neat.ClientGenomeState) {
+			close(state.SendInput())
+			state.SendBehaviour() <- []float64{float64(i * i)}
+			state.SendFitness() <- 1
+			close(state.SendFitness())
+		}(i, state)
+	}
+	pop, err = neat.RunGeneration(pop)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, pop.NoveltyArchive)
+	assert.Len(t, pop.Genomes, cfg.PopulationSize)
+}
@@ -1,25 +1,30 @@
 package neat
 
 import (
+	"errors"
 	"fmt"
 	"github.com/jmwri/neatgo/network"
-	"github.com/jmwri/neatgo/util"
 	"math"
 	"sync"
 )
 
+var ErrPopulationExtinct = errors.New("all species are extinct")
+
 func GeneratePopulation(cfg Config) (Population, error) {
 	genomes := make([]Genome, cfg.PopulationSize)
-	genomeStates := make([]GenomeState, cfg.PopulationSize)
 	pop := Population{
 		Cfg:                   cfg,
 		Genomes:               genomes,
-		GenomeStates:          genomeStates,
 		GenomeFitness:         make([]float64, cfg.PopulationSize),
 		Species:               make([]Species, 0),
 		Generation:            0,
 		BestEverGenomeFitness: math.Inf(-1),
 		BestGenomeFitness:     math.Inf(-1),
+
+		SpeciesCompatThreshold: cfg.SpeciesCompatThreshold,
+		DistanceCache:          NewDistanceCache(),
+		SpeciesHistory:         make(map[int][]SpeciesRecord),
+		FitnessHistory:         make(map[uint64][]float64),
 	}
 	var err error
 	for i := 0; i < cfg.PopulationSize; i++ {
@@ -34,15 +39,51 @@ func GeneratePopulation(cfg Config) (Population, error) {
 type Population struct {
 	Cfg     Config
 	Genomes []Genome
-	// GenomeStates contains a GenomeState and should be used for the Genome as the same index.
-	GenomeStates          []GenomeState
-	GenomeFitness         []float64
+	// GenomeStates contains a GenomeState for each evaluation of each Genome. The GenomeState at index i evaluates the
+	// Genome at index i % len(Genomes), so there are Cfg.EvaluationRepeats states for every Genome.
+	GenomeStates  []GenomeState
+	GenomeFitness []float64
+	// RawFitness contains the fitness of each genome in the last evaluated generation, as it was evaluated, before
+	// any ranking, novelty scoring or shaping.
+	RawFitness []float64
+	// GenomeObjectives contains the objectives sent for the Genome at the same index, when Cfg.MultiObjective is set.
+	GenomeObjectives [][]float64
+	// GenomeBehaviours contains the behaviour sent for the Genome at the same index, when Cfg.NoveltySearch is set.
+	GenomeBehaviours [][]float64
+	// GenomeCaseErrors contains the error on each test case sent for the Genome at the same index, for use by
+	// LexicaseSelector.
+	GenomeCaseErrors [][]float64
+	// GenomeNovelty contains the novelty of the Genome at the same index in the last evaluated generation.
+	GenomeNovelty         []float64
 	Species               []Species
 	Generation            int
 	BestEverGenome        Genome
 	BestEverGenomeFitness float64
 	BestGenome            Genome
 	BestGenomeFitness     float64
+	// SpeciesCompatThreshold is the compatibility threshold currently used for speciation.
+	// It starts as Cfg.SpeciesCompatThreshold, and is adjusted each generation if Cfg.TargetSpeciesCount is set.
+	SpeciesCompatThreshold float64
+	// DistanceCache stores genome distances calculated during the current generation.
+	DistanceCache *DistanceCache
+	// LastSpeciesID is the ID given to the most recently created species.
+	LastSpeciesID int
+	// SpeciesHistory contains a SpeciesRecord for each generation a species was alive, keyed by species ID.
+	SpeciesHistory map[int][]SpeciesRecord
+	// ParetoFront contains the non-dominated genomes of the last evaluated generation, when Cfg.MultiObjective is set.
+	ParetoFront []ParetoSolution
+	// NoveltyArchive contains the behaviours of novel genomes from previous generations.
+	NoveltyArchive [][]float64
+	// HallOfFame contains the best distinct genomes seen so far, fittest first.
+	HallOfFame []HallOfFameEntry
+	// FitnessHistory contains the most recent fitness evaluations of each genome, keyed by genome fingerprint.
+	FitnessHistory map[uint64][]float64
+
+	// Results received from each GenomeState, before they are combined for each Genome.
+	stateFitness    []float64
+	stateObjectives [][]float64
+	stateBehaviours [][]float64
+	stateCaseErrors [][]float64
 }
 
 func (p Population) States() []ClientGenomeState {
@@ -58,6 +99,15 @@ type ClientGenomeState interface {
 	SendInput() chan<- []float64
 	// SendFitness returns a channel where the client can send the fitness of the genome.
 	SendFitness() chan<- float64
+	// SendObjectives returns a channel where the client can send the objectives of the genome, when the config is
+	// MultiObjective. Objectives must be sent after input is closed, and before the fitness.
+	SendObjectives() chan<- []float64
+	// SendBehaviour returns a channel where the client can send the behaviour characterisation of the genome, when the
+	// config uses NoveltySearch. The behaviour must be sent after input is closed, and before the fitness.
+	SendBehaviour() chan<- []float64
+	// SendCaseErrors returns a channel where the client can send the error of the genome on each test case, for use by
+	// LexicaseSelector. Case errors must be sent after input is closed, and before the fitness.
+	SendCaseErrors() chan<- []float64
 	// GetOutput returns a channel where the client can receive the output from the network.
 	GetOutput() <-chan []float64
 	// GetError returns a channel where errors can be received.
@@ -68,6 +118,12 @@ type BackendGenomeState interface {
 	GetInput() <-chan []float64
 	// GetFitness returns a channel where the backend can receive the fitness of the genome.
 	GetFitness() <-chan float64
+	// GetObjectives returns a channel where the backend can receive the objectives of the genome.
+	GetObjectives() <-chan []float64
+	// GetBehaviour returns a channel where the backend can receive the behaviour characterisation of the genome.
+	GetBehaviour() <-chan []float64
+	// GetCaseErrors returns a channel where the backend can receive the error of the genome on each test case.
+	GetCaseErrors() <-chan []float64
 	// SendOutput returns a channel where the backend can send the output from the network.
 	SendOutput() chan<- []float64
 	// SendError returns a channel where the backend can send any errors.
@@ -75,10 +131,13 @@ type BackendGenomeState interface {
 }
 
 type genomeState struct {
-	inputCh   chan []float64
-	fitnessCh chan float64
-	outputCh  chan []float64
-	errCh     chan error
+	inputCh      chan []float64
+	fitnessCh    chan float64
+	objectivesCh chan []float64
+	behaviourCh  chan []float64
+	caseErrorsCh chan []float64
+	outputCh     chan []float64
+	errCh        chan error
 }
 
 func (s genomeState) SendInput() chan<- []float64 {
@@ -97,6 +156,30 @@ func (s genomeState) GetFitness() <-chan float64 {
 	return s.fitnessCh
 }
 
+func (s genomeState) SendObjectives() chan<- []float64 {
+	return s.objectivesCh
+}
+
+func (s genomeState) GetObjectives() <-chan []float64 {
+	return s.objectivesCh
+}
+
+func (s genomeState) SendBehaviour() chan<- []float64 {
+	return s.behaviourCh
+}
+
+func (s genomeState) GetBehaviour() <-chan []float64 {
+	return s.behaviourCh
+}
+
+func (s genomeState) SendCaseErrors() chan<- []float64 {
+	return s.caseErrorsCh
+}
+
+func (s genomeState) GetCaseErrors() <-chan []float64 {
+	return s.caseErrorsCh
+}
+
 func (s genomeState) SendOutput() chan<- []float64 {
 	return s.outputCh
 }
@@ -118,44 +201,172 @@ type GenomeState interface {
 	BackendGenomeState
 }
 
-func RunGeneration(pop Population) Population {
-	pop.Generation++
+// RunGeneration evaluates every genome through its GenomeState, then breeds the next generation with NextGeneration.
+// If every species goes extinct and Cfg.ResetOnExtinction is false, ErrPopulationExtinct is returned.
+func RunGeneration(pop Population) (Population, error) {
+	return NextGeneration(evaluateGenomes(pop))
+}
+
+// evaluateGenomes waits for every GenomeState to finish, and sets the fitness of each genome from their results.
+func evaluateGenomes(pop Population) Population {
+	report(pop, func(r Reporter) {
+		r.GenerationStart(pop)
+	})
 	wg := sync.WaitGroup{}
-	wg.Add(len(pop.Genomes))
-	for i := range pop.Genomes {
+	wg.Add(len(pop.GenomeStates))
+	for i := range pop.GenomeStates {
 		go runGenome(&wg, pop, i)
 	}
 
 	// Wait for all genomes in population to finish.
 	wg.Wait()
+	return combineEvaluations(pop)
+}
+
+// NextGeneration breeds the next generation from the evaluated genomes, using pop.GenomeFitness and any objectives or
+// behaviours. It is called by RunGeneration, and can be called directly when fitness is calculated without GenomeStates.
+// If every species goes extinct and Cfg.ResetOnExtinction is false, ErrPopulationExtinct is returned.
+func NextGeneration(pop Population) (Population, error) {
+	pop.Generation++
+	if pop.DistanceCache != nil {
+		pop.DistanceCache.Reset()
+	}
 
-	pop = Speciate(pop)
+	pop.RawFitness = append([]float64{}, pop.GenomeFitness...)
+	if pop.Cfg.MultiObjective {
+		pop = RankObjectives(pop)
+	}
+	report(pop, func(r Reporter) {
+		r.PostEvaluation(pop)
+	})
+	// Ranked objectives only compare genomes within a generation, so the best genome is chosen by raw fitness.
+	bestEverFitness := pop.BestEverGenomeFitness
+	pop = recordBestGenome(pop, pop.RawFitness)
+	if pop.BestEverGenomeFitness > bestEverFitness {
+		report(pop, func(r Reporter) {
+			r.NewBestGenome(pop, pop.BestEverGenome, pop.BestEverGenomeFitness)
+		})
+	}
+	if pop.Cfg.NoveltySearch {
+		pop = ScoreNovelty(pop)
+	}
+	pop = ShapeFitness(pop)
+	lastSpeciesID := pop.LastSpeciesID
+	previousSpecies := pop.Species
+	pop = speciate(pop, pop.RawFitness)
+	// Report the latest state of species that go extinct later in the generation.
+	previousSpecies = append(append([]Species{}, pop.Species...), previousSpecies...)
+	for _, species := range pop.Species {
+		if species.ID > lastSpeciesID {
+			species := species
+			report(pop, func(r Reporter) {
+				r.SpeciesCreated(pop, species)
+			})
+		}
+	}
+	pop = UpdateHallOfFame(pop, pop.RawFitness)
 	pop = RankSpecies(pop)
 	pop = CullSpecies(pop)
+	pop = NormaliseFitness(pop)
 	pop = FitnessSharing(pop)
+	beforeStaleness := pop.Species
 	pop = KillStaleSpecies(pop)
+	for _, species := range removedSpecies(beforeStaleness, pop.Species) {
+		species := species
+		report(pop, func(r Reporter) {
+			r.Stagnation(pop, species)
+		})
+	}
 	pop = KillBadSpecies(pop)
+	if len(pop.Species) == 0 {
+		reportExtinctSpecies(pop, previousSpecies)
+		pop, err := resetExtinctPopulation(pop)
+		if err != nil {
+			return pop, err
+		}
+		report(pop, func(r Reporter) {
+			r.GenerationEnd(pop)
+		})
+		return pop, nil
+	}
 	pop = Evolve(pop)
+	reportExtinctSpecies(pop, previousSpecies)
+
+	// Build fresh genome states for next generation.
+	pop = buildGenomeStates(pop)
+	report(pop, func(r Reporter) {
+		r.GenerationEnd(pop)
+	})
+	return pop, nil
+}
 
-	highestFitness := math.Inf(-1)
-	for genomeID, fitness := range pop.GenomeFitness {
-		if fitness > highestFitness {
-			highestFitness = fitness
-			pop.BestGenomeFitness = fitness
-			pop.BestGenome = pop.Genomes[genomeID]
+// removedSpecies returns the species in before that aren't in after.
+func removedSpecies(before, after []Species) []Species {
+	alive := make(map[int]bool)
+	for _, species := range after {
+		alive[species.ID] = true
+	}
+	removed := make([]Species, 0)
+	reported := make(map[int]bool)
+	for _, species := range before {
+		if !alive[species.ID] && !reported[species.ID] {
+			reported[species.ID] = true
+			removed = append(removed, species)
 		}
 	}
-	if pop.BestGenomeFitness > pop.BestEverGenomeFitness {
-		pop.BestEverGenomeFitness = pop.BestGenomeFitness
-		pop.BestEverGenome = pop.BestGenome
+	return removed
+}
+
+// reportExtinctSpecies notifies reporters of every species in previousSpecies that is no longer in the population.
+func reportExtinctSpecies(pop Population, previousSpecies []Species) {
+	if len(pop.Cfg.Reporters) == 0 {
+		return
+	}
+	for _, species := range removedSpecies(previousSpecies, pop.Species) {
+		species := species
+		report(pop, func(r Reporter) {
+			r.SpeciesExtinct(pop, species)
+		})
 	}
+}
 
-	// Build fresh genome states for next generation.
-	return buildGenomeStates(pop)
+// resetExtinctPopulation replaces a population with no species left, if Cfg.ResetOnExtinction allows it.
+// The new genomes are either randomly generated, or mutated copies of the best ever genome.
+func resetExtinctPopulation(pop Population) (Population, error) {
+	report(pop, func(r Reporter) {
+		r.Extinction(pop)
+	})
+	if !pop.Cfg.ResetOnExtinction {
+		return pop, fmt.Errorf("generation %d: %w", pop.Generation, ErrPopulationExtinct)
+	}
+
+	fromBestEver := pop.Cfg.ResetFromBestEverGenome && pop.BestEverGenome.NumNodes() > 0
+	genomes := make([]Genome, pop.Cfg.PopulationSize)
+	for i := range genomes {
+		if !fromBestEver {
+			genome, err := GenerateGenome(pop.Cfg)
+			if err != nil {
+				return pop, fmt.Errorf("failed to generate genome: %w", err)
+			}
+			genomes[i] = genome
+			continue
+		}
+		genomes[i] = CopyGenome(pop.BestEverGenome)
+		// Keep one unchanged copy of the best ever genome.
+		if i > 0 {
+			genomes[i] = MutateGenome(pop.Cfg, genomes[i])
+		}
+	}
+
+	pop.Genomes = genomes
+	pop.GenomeFitness = make([]float64, len(genomes))
+	pop.Species = make([]Species, 0)
+	return buildGenomeStates(pop), nil
 }
 
+// runGenome activates the genome evaluated by the GenomeState at index i.
 func runGenome(wg *sync.WaitGroup, pop Population, i int) {
-	genome := pop.Genomes[i]
+	genome := pop.Genomes[i%len(pop.Genomes)]
 	var state BackendGenomeState = pop.GenomeStates[i]
 	defer wg.Done()
 	defer close(state.SendOutput())
@@ -164,12 +375,7 @@ func runGenome(wg *sync.WaitGroup, pop Population, i int) {
 		input, ok := <-state.GetInput()
 		if !ok {
 			// If input is closed, then game has finished.
-			fitness, ok := <-state.GetFitness()
-			if !ok {
-				state.SendError() <- fmt.Errorf("failed to receive fitness")
-				return
-			}
-			pop.GenomeFitness[i] = fitness
+			receiveResults(pop, state, i)
 			return
 		}
 		output, err := network.Activate(genome.Layers.Nodes(), genome.Connections, input)
@@ -181,68 +387,105 @@ func runGenome(wg *sync.WaitGroup, pop Population, i int) {
 	}
 }
 
+// receiveResults stores the results of a finished game for the GenomeState at index i.
+// Results such as objectives are optional, and the fitness is always the last result sent.
+func receiveResults(pop Population, state BackendGenomeState, i int) {
+	objectivesCh := state.GetObjectives()
+	behaviourCh := state.GetBehaviour()
+	caseErrorsCh := state.GetCaseErrors()
+	for {
+		select {
+		case objectives, ok := <-objectivesCh:
+			if !ok {
+				// Stop receiving from a closed channel.
+				objectivesCh = nil
+				continue
+			}
+			pop.stateObjectives[i] = objectives
+		case behaviour, ok := <-behaviourCh:
+			if !ok {
+				behaviourCh = nil
+				continue
+			}
+			pop.stateBehaviours[i] = behaviour
+		case caseErrors, ok := <-caseErrorsCh:
+			if !ok {
+				caseErrorsCh = nil
+				continue
+			}
+			pop.stateCaseErrors[i] = caseErrors
+		case fitness, ok := <-state.GetFitness():
+			if !ok {
+				state.SendError() <- fmt.Errorf("failed to receive fitness")
+				return
+			}
+			pop.stateFitness[i] = fitness
+			return
+		}
+	}
+}
+
 func buildGenomeStates(pop Population) Population {
+	pop.GenomeStates = make([]GenomeState, len(pop.Genomes)*evaluationRepeats(pop.Cfg))
 	for i := range pop.GenomeStates {
 		pop.GenomeStates[i] = genomeState{
-			inputCh:   make(chan []float64),
-			fitnessCh: make(chan float64),
-			outputCh:  make(chan []float64),
-			errCh:     make(chan error),
+			inputCh:      make(chan []float64),
+			fitnessCh:    make(chan float64),
+			objectivesCh: make(chan []float64),
+			behaviourCh:  make(chan []float64),
+			caseErrorsCh: make(chan []float64),
+			outputCh:     make(chan []float64),
+			errCh:        make(chan error),
 		}
 	}
+	pop.GenomeObjectives = make([][]float64, len(pop.Genomes))
+	pop.GenomeBehaviours = make([][]float64, len(pop.Genomes))
+	pop.GenomeCaseErrors = make([][]float64, len(pop.Genomes))
+	pop.stateFitness = make([]float64, len(pop.GenomeStates))
+	pop.stateObjectives = make([][]float64, len(pop.GenomeStates))
+	pop.stateBehaviours = make([][]float64, len(pop.GenomeStates))
+	pop.stateCaseErrors = make([][]float64, len(pop.GenomeStates))
 	return pop
 }
 
+// Evolve replaces the population with the next generation. Each species gets the number of offspring allocated by
+// its share of fitness, starting with its elites unchanged, so the new population always has Cfg.PopulationSize genomes
+// and every genome belongs to a species.
 func Evolve(pop Population) Population {
 	offspringCount := getDesiredOffspringCount(pop)
-	newGenomes := make([]Genome, 0)
-	newFitness := make([]float64, 0)
+	newGenomes := make([]Genome, 0, pop.Cfg.PopulationSize)
+	newFitness := make([]float64, 0, pop.Cfg.PopulationSize)
 	newSpecies := make([]Species, 0)
 
-	topSpeciesGenomes := make([]int, 0)
-
 	for i, species := range pop.Species {
-		// Take the top N genomes from each species for reproduction later
-		for j := 0; j < pop.Cfg.TopGenomesFromSpeciesToFill; j++ {
-			if j >= len(species.Genomes) {
-				break
-			}
-			oldGenomeIndex := species.Genomes[j]
-			topSpeciesGenomes = append(topSpeciesGenomes, oldGenomeIndex)
-		}
-
-		numOffspring, ok := offspringCount[i]
-		if !ok {
+		numOffspring := offspringCount[i]
+		if numOffspring == 0 || len(species.Genomes) == 0 {
 			continue
 		}
-		if numOffspring < pop.Cfg.MinSpeciesSize {
-			numOffspring = pop.Cfg.MinSpeciesSize
-		}
 
 		speciesGenomes := make([]int, 0)
 
 		elitism := pop.Cfg.Elitism
-		// Should only happen on the first run... Don't try to carry over more genomes than exist.
+		// Don't try to carry over more genomes than exist, or than the species is allowed.
 		if elitism > len(species.Genomes) {
 			elitism = len(species.Genomes)
 		}
+		if elitism > numOffspring {
+			elitism = numOffspring
+		}
 
-		// Add elite genomes from each species with no mutation.
+		// Add elite genomes from each species with no mutation. They are evaluated again like every other genome, so
+		// their previous fitness isn't carried over.
 		for j := 0; j < elitism; j++ {
 			oldGenomeIndex := species.Genomes[j]
 			newGenomeIndex := len(newGenomes)
 			newGenomes = append(newGenomes, pop.Genomes[oldGenomeIndex])
-			newFitness = append(newFitness, pop.GenomeFitness[oldGenomeIndex])
+			newFitness = append(newFitness, 0)
 			speciesGenomes = append(speciesGenomes, newGenomeIndex)
 		}
 
 		// Fill the remaining allowance with mutated offspring.
-		for j := elitism; j < numOffspring; j++ {
-			if len(pop.Species[i].Genomes) == 0 {
-				continue
-			}
-			// Get offspring from the species
-			offspring := GetOffspring(pop, pop.Species[i])
+		for _, offspring := range getOffspring(pop, pop.Species[i], numOffspring-elitism) {
 			newGenomeIndex := len(newGenomes)
 			newGenomes = append(newGenomes, offspring)
 			newFitness = append(newFitness, 0)
@@ -252,26 +495,8 @@ func Evolve(pop Population) Population {
 		newSpecies = append(newSpecies, species)
 	}
 
-	// Once processed all species, if we have some population size left over, crossover the best genomes of all species.
-	// Don't add these genomes to any species.
-	if len(newGenomes) < pop.Cfg.PopulationSize && len(topSpeciesGenomes) != 0 {
-		for len(newGenomes) < pop.Cfg.PopulationSize {
-			a := util.RandSliceElement(topSpeciesGenomes)
-			b := util.RandSliceElement(topSpeciesGenomes)
-			if pop.GenomeFitness[a] < pop.GenomeFitness[b] {
-				a, b = b, a
-			}
-			aGenome := pop.Genomes[a]
-			bGenome := pop.Genomes[b]
-			baby := Crossover(pop.Cfg, aGenome, bGenome)
-			newGenomes = append(newGenomes, baby)
-			newFitness = append(newFitness, 0)
-		}
-	}
-
 	pop.Genomes = newGenomes
 	pop.GenomeFitness = newFitness
 	pop.Species = newSpecies
-	pop.GenomeStates = make([]GenomeState, len(newGenomes))
 	return pop
 }
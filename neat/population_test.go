@@ -1,6 +1,7 @@
 package neat_test
 
 import (
+	"errors"
 	"fmt"
 	"github.com/jmwri/neatgo/neat"
 	"github.com/stretchr/testify/assert"
@@ -15,10 +16,161 @@ func TestRunGeneration(t *testing.T) {
 	pop, err := neat.GeneratePopulation(cfg)
 	assert.NoError(t, err)
 
-	pop = playGame(pop)
+	pop = playGame(t, pop)
 }
 
-func playGame(pop neat.Population) neat.Population {
+// runGenerationWithFitness runs a generation where each genome is given a fitness without playing a game.
+func runGenerationWithFitness(pop neat.Population, fitness func(i int) float64) (neat.Population, error) {
+	for i, state := range pop.States() {
+		go func(i int, state neat.ClientGenomeState) {
+			close(state.SendInput())
+			state.SendFitness() <- fitness(i)
+			close(state.SendFitness())
+		}(i, state)
+	}
+	return neat.RunGeneration(pop)
+}
+
+func extinctionConfig() neat.Config {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	// Every species is stale as soon as it is created, and none are protected.
+	cfg.SpeciesElitism = 0
+	cfg.SpeciesStalenessThreshold = 0
+	return cfg
+}
+
+func TestRunGeneration_Extinction(t *testing.T) {
+	cfg := extinctionConfig()
+	hookCalls := 0
+	cfg.Reporters = []neat.Reporter{neat.ExtinctionFunc(func(pop neat.Population) {
+		hookCalls++
+	})}
+	// The deprecated hook is still called.
+	cfg.OnExtinction = func(pop neat.Population) {
+		hookCalls++
+	}
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	_, err = runGenerationWithFitness(pop, func(i int) float64 {
+		return float64(i)
+	})
+	assert.True(t, errors.Is(err, neat.ErrPopulationExtinct))
+	assert.Equal(t, 2, hookCalls)
+}
+
+func TestRunGeneration_ResetOnExtinction(t *testing.T) {
+	cfg := extinctionConfig()
+	cfg.ResetOnExtinction = true
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	oldFingerprints := make(map[uint64]bool)
+	for _, genome := range pop.Genomes {
+		oldFingerprints[genome.Fingerprint()] = true
+	}
+
+	pop, err = runGenerationWithFitness(pop, func(i int) float64 {
+		return float64(i)
+	})
+	assert.NoError(t, err)
+	assert.Len(t, pop.Genomes, cfg.PopulationSize)
+	assert.Len(t, pop.States(), cfg.PopulationSize)
+	assert.Empty(t, pop.Species)
+	for _, genome := range pop.Genomes {
+		assert.False(t, oldFingerprints[genome.Fingerprint()], "genome should have been regenerated")
+	}
+
+	// The reset population can carry on evolving.
+	_, err = runGenerationWithFitness(pop, func(i int) float64 {
+		return float64(i)
+	})
+	assert.NoError(t, err)
+}
+
+func TestRunGeneration_ResetFromBestEverGenome(t *testing.T) {
+	cfg := extinctionConfig()
+	cfg.ResetOnExtinction = true
+	cfg.ResetFromBestEverGenome = true
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	best := pop.Genomes[3]
+
+	pop, err = runGenerationWithFitness(pop, func(i int) float64 {
+		if i == 3 {
+			return 10
+		}
+		return 1
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, best.Fingerprint(), pop.BestEverGenome.Fingerprint())
+	assert.Len(t, pop.Genomes, cfg.PopulationSize)
+	assert.Equal(t, best.Fingerprint(), pop.Genomes[0].Fingerprint())
+}
+
+func TestRunGeneration_InfiniteFitness(t *testing.T) {
+	tests := []struct {
+		name     string
+		infinite []float64
+	}{
+		{name: "positive", infinite: []float64{math.Inf(1)}},
+		{name: "negative", infinite: []float64{math.Inf(-1)}},
+		{name: "both", infinite: []float64{math.Inf(1), math.Inf(-1)}},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			cfg := neat.DefaultConfig(2, 1)
+			cfg.PopulationSize = 20
+			pop, err := neat.GeneratePopulation(cfg)
+			assert.NoError(t, err)
+
+			for generation := 0; generation < 3; generation++ {
+				pop, err = runGenerationWithFitness(pop, func(i int) float64 {
+					if i < len(test.infinite) {
+						return test.infinite[i]
+					}
+					return float64(i - 10)
+				})
+				assert.NoError(t, err)
+				assertSpeciesCoverPopulation(t, pop)
+			}
+		})
+	}
+}
+
+func TestRunGeneration_NegativeFitness(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 20
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	for pop.Generation < 5 {
+		clientStates := pop.States()
+		wg := sync.WaitGroup{}
+		wg.Add(len(clientStates))
+		for _, state := range clientStates {
+			go func(state neat.ClientGenomeState) {
+				defer wg.Done()
+				state.SendInput() <- []float64{1}
+				output := <-state.GetOutput()
+				close(state.SendInput())
+				// Score as a negative loss, so every genome has negative fitness.
+				state.SendFitness() <- -1 - math.Abs(output[0]-.5)
+				close(state.SendFitness())
+			}(state)
+		}
+		pop, err = neat.RunGeneration(pop)
+		assert.NoError(t, err)
+		wg.Wait()
+
+		assert.Less(t, pop.BestGenomeFitness, -1.0)
+		assert.GreaterOrEqual(t, pop.BestGenomeFitness, -1.5)
+		assertSpeciesCoverPopulation(t, pop)
+	}
+}
+
+func playGame(t *testing.T, pop neat.Population) neat.Population {
 	for pop.Generation < 10 {
 		clientStates := pop.States()
 		wg := sync.WaitGroup{}
@@ -59,7 +211,9 @@ func playGame(pop neat.Population) neat.Population {
 				}
 			}(state)
 		}
-		pop = neat.RunGeneration(pop)
+		var err error
+		pop, err = neat.RunGeneration(pop)
+		assert.NoError(t, err)
 		wg.Wait()
 
 		speciesBestFitnesses := make([]float64, len(pop.Species))
@@ -0,0 +1,201 @@
+package neat
+
+import (
+	"fmt"
+	"github.com/jmwri/neatgo/network"
+	"math"
+)
+
+type RealTimeConfig struct {
+	ReplacementInterval int // How many ticks between replacing the worst genome. 0 disables replacement in RealTimeTick.
+	MinEvaluationTicks  int // How many ticks a genome must be alive before it can be replaced.
+}
+
+func DefaultRealTimeConfig() RealTimeConfig {
+	return RealTimeConfig{
+		ReplacementInterval: 20,
+		MinEvaluationTicks:  100,
+	}
+}
+
+// RealTimePopulation evolves one genome at a time, so every genome can stay in use in a persistent world.
+// Instead of sending fitness through a GenomeState, the world updates GenomeFitness as each genome is evaluated.
+type RealTimePopulation struct {
+	Population
+	RealTimeCfg RealTimeConfig
+	Tick        int
+	// GenomeBirthTick contains the tick that the Genome at the same index was created.
+	GenomeBirthTick []int
+	Replacements    int
+}
+
+// Replacement describes a genome that was replaced by a new offspring.
+type Replacement struct {
+	// Index is the index of the replaced Genome, and of its replacement.
+	Index          int
+	Removed        Genome
+	RemovedFitness float64
+	Genome         Genome
+	SpeciesID      int
+}
+
+func GenerateRealTimePopulation(cfg Config, rtCfg RealTimeConfig) (RealTimePopulation, error) {
+	pop, err := GeneratePopulation(cfg)
+	if err != nil {
+		return RealTimePopulation{}, err
+	}
+	return RealTimePopulation{
+		Population:      Speciate(pop),
+		RealTimeCfg:     rtCfg,
+		GenomeBirthTick: make([]int, len(pop.Genomes)),
+	}, nil
+}
+
+// Activate runs input through the network of the genome at index i.
+func (p RealTimePopulation) Activate(i int, input []float64) ([]float64, error) {
+	genome := p.Genomes[i]
+	return network.Activate(genome.Layers.Nodes(), genome.Connections, input)
+}
+
+// RealTimeTick advances the population by one tick, and calls ReplaceWorstGenome every RealTimeCfg.ReplacementInterval
+// ticks. The Replacement is nil if no genome was replaced.
+func RealTimeTick(rt RealTimePopulation) (RealTimePopulation, *Replacement, error) {
+	rt.Tick++
+	if rt.RealTimeCfg.ReplacementInterval <= 0 || rt.Tick%rt.RealTimeCfg.ReplacementInterval != 0 {
+		return rt, nil, nil
+	}
+	return ReplaceWorstGenome(rt)
+}
+
+// ReplaceWorstGenome removes the genome with the lowest shared fitness that has been alive for at least
+// RealTimeCfg.MinEvaluationTicks. It is replaced by an offspring of a species chosen in proportion to its average shared
+// fitness, which is then added to the first compatible species. The Replacement is nil if no genome has been
+// evaluated for long enough.
+func ReplaceWorstGenome(rt RealTimePopulation) (RealTimePopulation, *Replacement, error) {
+	pop := rt.Population
+	speciesOf := make(map[int]int)
+	for i, species := range pop.Species {
+		for _, genomeIndex := range species.Genomes {
+			speciesOf[genomeIndex] = i
+		}
+	}
+
+	normalise := pop.Cfg.FitnessNormalisation
+	if normalise == nil {
+		normalise = MinShiftFitness
+	}
+	adjusted := normalise(pop.GenomeFitness)
+	for i := range adjusted {
+		adjusted[i] /= float64(len(pop.Species[speciesOf[i]].Genomes))
+	}
+
+	worst := -1
+	for i := range pop.Genomes {
+		if rt.Tick-rt.GenomeBirthTick[i] < rt.RealTimeCfg.MinEvaluationTicks {
+			continue
+		}
+		if worst == -1 || adjusted[i] < adjusted[worst] {
+			worst = i
+		}
+	}
+	if worst == -1 {
+		return rt, nil, nil
+	}
+	pop = recordBestGenome(pop, pop.GenomeFitness)
+
+	replacement := &Replacement{
+		Index:          worst,
+		Removed:        pop.Genomes[worst],
+		RemovedFitness: pop.GenomeFitness[worst],
+	}
+
+	// Remove the worst genome from its species, and update every species' fitness.
+	species := make([]Species, 0, len(pop.Species))
+	for _, s := range pop.Species {
+		members := make([]int, 0, len(s.Genomes))
+		totalFitness := 0.0
+		s.BestFitness = math.Inf(-1)
+		for _, genomeIndex := range s.Genomes {
+			if genomeIndex == worst {
+				continue
+			}
+			members = append(members, genomeIndex)
+			totalFitness += adjusted[genomeIndex]
+			s.BestFitness = math.Max(s.BestFitness, pop.GenomeFitness[genomeIndex])
+		}
+		if len(members) == 0 {
+			continue
+		}
+		s.Genomes = members
+		s.AvgFitness = totalFitness / float64(len(members))
+		species = append(species, s)
+	}
+	pop.Species = species
+
+	var baby Genome
+	if len(pop.Species) == 0 {
+		// There is nothing left to breed from.
+		var err error
+		baby, err = GenerateGenome(pop.Cfg)
+		if err != nil {
+			return rt, nil, fmt.Errorf("failed to generate genome: %w", err)
+		}
+	} else {
+		speciesIndexes := make([]int, len(pop.Species))
+		weights := make([]float64, len(pop.Species))
+		weightSum := 0.0
+		for i, s := range pop.Species {
+			speciesIndexes[i] = i
+			weights[i] = s.AvgFitness
+			weightSum += weights[i]
+		}
+		if weightSum <= 0 {
+			for i := range weights {
+				weights[i] = 1
+			}
+		}
+		parentSpecies := pop.Species[weightedSelect(speciesIndexes, weights, 1)[0]]
+		// Only the members that survive the cull can be parents.
+		survivors := sortByFitness(pop, parentSpecies.Genomes)
+		numSurvivors := int(math.Ceil(pop.Cfg.SurvivalThreshold * float64(len(survivors))))
+		if numSurvivors < 1 {
+			numSurvivors = 1
+		}
+		parentSpecies.Genomes = survivors[:numSurvivors]
+		baby = GetOffspring(pop, parentSpecies)
+	}
+
+	pop.Genomes[worst] = baby
+	pop.GenomeFitness[worst] = 0
+	rt.GenomeBirthTick[worst] = rt.Tick
+
+	if pop.DistanceCache != nil {
+		pop.DistanceCache.Reset()
+	}
+	pop, replacement.SpeciesID = addToSpecies(pop, worst)
+	pop = adjustSpeciesCompatThreshold(pop)
+
+	replacement.Genome = baby
+	rt.Population = pop
+	rt.Replacements++
+	return rt, replacement, nil
+}
+
+// addToSpecies adds the genome at genomeIndex to the first compatible species, or a new species if none are
+// compatible, and returns the species ID.
+func addToSpecies(pop Population, genomeIndex int) (Population, int) {
+	genome := pop.Genomes[genomeIndex]
+	for i, species := range pop.Species {
+		if CompatibleWithSpecies(pop, species, genome) {
+			pop.Species[i].Genomes = append(pop.Species[i].Genomes, genomeIndex)
+			return pop, species.ID
+		}
+	}
+	species := NewSpecies(genome)
+	pop.LastSpeciesID++
+	species.ID = pop.LastSpeciesID
+	species.CreatedGeneration = pop.Generation
+	species.Genomes = append(species.Genomes, genomeIndex)
+	pop.Species = append(pop.Species, species)
+	return pop, species.ID
+}
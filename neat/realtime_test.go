@@ -0,0 +1,84 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRealTimeTick(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	rt, err := neat.GenerateRealTimePopulation(cfg, neat.RealTimeConfig{
+		ReplacementInterval: 5,
+		MinEvaluationTicks:  10,
+	})
+	assert.NoError(t, err)
+	assertSpeciesCoverPopulation(t, rt.Population)
+
+	replacements := 0
+	for i := 0; i < 30; i++ {
+		var replacement *neat.Replacement
+		rt, replacement, err = neat.RealTimeTick(rt)
+		assert.NoError(t, err)
+		if replacement == nil {
+			continue
+		}
+		replacements++
+		// No genome is old enough before tick 10.
+		assert.GreaterOrEqual(t, rt.Tick, 10)
+		assert.Equal(t, 0, rt.Tick%5)
+		assert.Equal(t, rt.Tick, rt.GenomeBirthTick[replacement.Index])
+		assert.Equal(t, replacement.Genome.Fingerprint(), rt.Genomes[replacement.Index].Fingerprint())
+		assert.Empty(t, neat.ValidateGenome(cfg, replacement.Genome))
+		assert.Len(t, rt.Genomes, 10)
+		assertSpeciesCoverPopulation(t, rt.Population)
+
+		_, err = rt.Activate(replacement.Index, []float64{1, 0})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 5, replacements)
+	assert.Equal(t, 5, rt.Replacements)
+}
+
+func TestReplaceWorstGenome(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	// Put every genome in one species, so that shared fitness has the same order as fitness.
+	cfg.SpeciesCompatThreshold = 1000
+	rt, err := neat.GenerateRealTimePopulation(cfg, neat.RealTimeConfig{MinEvaluationTicks: 5})
+	assert.NoError(t, err)
+	assert.Len(t, rt.Species, 1)
+
+	for i := range rt.GenomeFitness {
+		rt.GenomeFitness[i] = float64(10 + i)
+	}
+	// The worst genome is too young to be replaced.
+	rt.GenomeFitness[3] = 0
+	rt.GenomeBirthTick[3] = 3
+	rt.GenomeFitness[7] = 1
+	rt.Tick = 6
+
+	removed := rt.Genomes[7]
+	rt, replacement, err := neat.ReplaceWorstGenome(rt)
+	assert.NoError(t, err)
+	assert.NotNil(t, replacement)
+	assert.Equal(t, 7, replacement.Index)
+	assert.Equal(t, 1., replacement.RemovedFitness)
+	assert.Equal(t, removed.Fingerprint(), replacement.Removed.Fingerprint())
+	assert.Equal(t, 0., rt.GenomeFitness[7])
+	assert.Equal(t, 19., rt.BestEverGenomeFitness)
+	assert.Len(t, rt.Species, 1)
+	assertSpeciesCoverPopulation(t, rt.Population)
+}
+
+func TestReplaceWorstGenome_NoneEvaluated(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 5
+	rt, err := neat.GenerateRealTimePopulation(cfg, neat.RealTimeConfig{MinEvaluationTicks: 5})
+	assert.NoError(t, err)
+	rt, replacement, err := neat.ReplaceWorstGenome(rt)
+	assert.NoError(t, err)
+	assert.Nil(t, replacement)
+	assert.Equal(t, 0, rt.Replacements)
+}
@@ -0,0 +1,210 @@
+package neat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reporter is notified of events during each generation. Reporters are registered with Config.Reporters, and are
+// called from the goroutine that runs the generation, so a Reporter shared between islands must be safe for concurrent
+// use. Embed BaseReporter to only implement some events.
+type Reporter interface {
+	// GenerationStart is called before the genomes of a generation are evaluated.
+	GenerationStart(pop Population)
+	// PostEvaluation is called once every genome has been evaluated, before speciation and breeding.
+	PostEvaluation(pop Population)
+	SpeciesCreated(pop Population, species Species)
+	SpeciesExtinct(pop Population, species Species)
+	// NewBestGenome is called when a genome beats the best ever fitness.
+	NewBestGenome(pop Population, genome Genome, fitness float64)
+	// Stagnation is called when a species is removed for not improving.
+	Stagnation(pop Population, species Species)
+	// Extinction is called when every species has gone extinct.
+	Extinction(pop Population)
+	// GenerationEnd is called once the next generation has been bred.
+	GenerationEnd(pop Population)
+}
+
+// BaseReporter ignores every event.
+type BaseReporter struct{}
+
+func (BaseReporter) GenerationStart(pop Population)                               {}
+func (BaseReporter) PostEvaluation(pop Population)                                {}
+func (BaseReporter) SpeciesCreated(pop Population, species Species)               {}
+func (BaseReporter) SpeciesExtinct(pop Population, species Species)               {}
+func (BaseReporter) NewBestGenome(pop Population, genome Genome, fitness float64) {}
+func (BaseReporter) Stagnation(pop Population, species Species)                   {}
+func (BaseReporter) Extinction(pop Population)                                    {}
+func (BaseReporter) GenerationEnd(pop Population)                                 {}
+
+// ExtinctionFunc is a Reporter that only reports Extinction, by calling itself.
+type ExtinctionFunc func(pop Population)
+
+func (ExtinctionFunc) GenerationStart(pop Population)                               {}
+func (ExtinctionFunc) PostEvaluation(pop Population)                                {}
+func (ExtinctionFunc) SpeciesCreated(pop Population, species Species)               {}
+func (ExtinctionFunc) SpeciesExtinct(pop Population, species Species)               {}
+func (ExtinctionFunc) NewBestGenome(pop Population, genome Genome, fitness float64) {}
+func (ExtinctionFunc) Stagnation(pop Population, species Species)                   {}
+func (f ExtinctionFunc) Extinction(pop Population)                                  { f(pop) }
+func (ExtinctionFunc) GenerationEnd(pop Population)                                 {}
+
+func report(pop Population, event func(r Reporter)) {
+	for _, reporter := range pop.Cfg.Reporters {
+		event(reporter)
+	}
+	if pop.Cfg.OnExtinction != nil {
+		event(ExtinctionFunc(pop.Cfg.OnExtinction))
+	}
+}
+
+func NewStdoutReporter() *TextReporter {
+	return NewTextReporter(os.Stdout)
+}
+
+func NewTextReporter(w io.Writer) *TextReporter {
+	return &TextReporter{w: w}
+}
+
+// TextReporter writes a human-readable summary of each generation.
+type TextReporter struct {
+	BaseReporter
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (r *TextReporter) Stagnation(pop Population, species Species) {
+	r.printf("Species %d stagnated after %d generations\n", species.ID, species.Staleness)
+}
+
+func (r *TextReporter) Extinction(pop Population) {
+	r.printf("All species extinct in generation %d\n", pop.Generation)
+}
+
+func (r *TextReporter) GenerationEnd(pop Population) {
+	r.printf(`Generation %d
+BestFitness: %f
+BestNodes: %d
+BestConnections: %d
+PopSize: %d
+NumSpecies: %d
+-------------------------
+`, pop.Generation, pop.BestGenomeFitness, pop.BestGenome.NumNodes(), pop.BestGenome.NumConnections(), len(pop.Genomes), len(pop.Species))
+}
+
+func (r *TextReporter) printf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprintf(r.w, format, args...)
+}
+
+func NewJSONLinesReporter(w io.Writer) *JSONLinesReporter {
+	return &JSONLinesReporter{enc: json.NewEncoder(w)}
+}
+
+// JSONLinesReporter writes every event as a line of JSON, such as
+// {"event":"generation-end","time":"...","generation":3,"best_fitness":3.2,"num_genomes":150,"num_species":4}.
+// Fitness that isn't a finite number is written as null.
+type JSONLinesReporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// ReportEvent is a line written by JSONLinesReporter.
+type ReportEvent struct {
+	Event          string    `json:"event"`
+	Time           time.Time `json:"time"`
+	Generation     int       `json:"generation"`
+	BestFitness    *float64  `json:"best_fitness,omitempty"`
+	NumGenomes     int       `json:"num_genomes,omitempty"`
+	NumSpecies     int       `json:"num_species,omitempty"`
+	SpeciesID      int       `json:"species_id,omitempty"`
+	SpeciesSize    int       `json:"species_size,omitempty"`
+	Fitness        *float64  `json:"fitness,omitempty"`
+	NumNodes       int       `json:"num_nodes,omitempty"`
+	NumConnections int       `json:"num_connections,omitempty"`
+}
+
+// Err returns the first error from writing an event.
+func (r *JSONLinesReporter) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *JSONLinesReporter) GenerationStart(pop Population) {
+	r.write(populationEvent("generation-start", pop))
+}
+
+func (r *JSONLinesReporter) PostEvaluation(pop Population) {
+	r.write(populationEvent("post-evaluation", pop))
+}
+
+func (r *JSONLinesReporter) SpeciesCreated(pop Population, species Species) {
+	r.write(speciesEvent("species-created", pop, species))
+}
+
+func (r *JSONLinesReporter) SpeciesExtinct(pop Population, species Species) {
+	r.write(speciesEvent("species-extinct", pop, species))
+}
+
+func (r *JSONLinesReporter) NewBestGenome(pop Population, genome Genome, fitness float64) {
+	event := populationEvent("new-best-genome", pop)
+	event.Fitness = finiteFloat(fitness)
+	event.NumNodes = genome.NumNodes()
+	event.NumConnections = genome.NumConnections()
+	r.write(event)
+}
+
+func (r *JSONLinesReporter) Stagnation(pop Population, species Species) {
+	r.write(speciesEvent("stagnation", pop, species))
+}
+
+func (r *JSONLinesReporter) Extinction(pop Population) {
+	r.write(populationEvent("extinction", pop))
+}
+
+func (r *JSONLinesReporter) GenerationEnd(pop Population) {
+	r.write(populationEvent("generation-end", pop))
+}
+
+func (r *JSONLinesReporter) write(event ReportEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(event); err != nil && r.err == nil {
+		r.err = fmt.Errorf("failed to write %s event: %w", event.Event, err)
+	}
+}
+
+func populationEvent(name string, pop Population) ReportEvent {
+	return ReportEvent{
+		Event:       name,
+		Time:        time.Now(),
+		Generation:  pop.Generation,
+		BestFitness: finiteFloat(pop.BestGenomeFitness),
+		NumGenomes:  len(pop.Genomes),
+		NumSpecies:  len(pop.Species),
+	}
+}
+
+func speciesEvent(name string, pop Population, species Species) ReportEvent {
+	event := populationEvent(name, pop)
+	event.SpeciesID = species.ID
+	event.SpeciesSize = len(species.Genomes)
+	event.Fitness = finiteFloat(species.BestFitness)
+	return event
+}
+
+// finiteFloat returns nil for values that can't be encoded as JSON.
+func finiteFloat(f float64) *float64 {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil
+	}
+	return &f
+}
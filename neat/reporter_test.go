@@ -0,0 +1,162 @@
+package neat_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingReporter records the name of every event.
+type recordingReporter struct {
+	neat.BaseReporter
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recordingReporter) record(event string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf(event, args...))
+}
+
+func (r *recordingReporter) GenerationStart(pop neat.Population) {
+	r.record("start")
+}
+
+func (r *recordingReporter) PostEvaluation(pop neat.Population) {
+	r.record("evaluated")
+}
+
+func (r *recordingReporter) SpeciesCreated(pop neat.Population, species neat.Species) {
+	r.record("created %d", species.ID)
+}
+
+func (r *recordingReporter) SpeciesExtinct(pop neat.Population, species neat.Species) {
+	r.record("extinct %d", species.ID)
+}
+
+func (r *recordingReporter) NewBestGenome(pop neat.Population, genome neat.Genome, fitness float64) {
+	r.record("best %.0f", fitness)
+}
+
+func (r *recordingReporter) Stagnation(pop neat.Population, species neat.Species) {
+	r.record("stagnated %d", species.ID)
+}
+
+func (r *recordingReporter) Extinction(pop neat.Population) {
+	r.record("extinction")
+}
+
+func (r *recordingReporter) GenerationEnd(pop neat.Population) {
+	r.record("end %d", pop.Generation)
+}
+
+func TestReporter_Events(t *testing.T) {
+	reporter := &recordingReporter{}
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	// Every genome is in its own species.
+	cfg.SpeciesCompatThreshold = 0
+	cfg.MinSpeciesCompatThreshold = 0
+	cfg.Reporters = []neat.Reporter{reporter}
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	pop, err = neat.EvaluateGeneration(pop, constantEvaluator(1))
+	assert.NoError(t, err)
+	expected := []string{"start", "evaluated", "best 1"}
+	for i := 1; i <= 10; i++ {
+		expected = append(expected, fmt.Sprintf("created %d", i))
+	}
+	// Each species gets one offspring, and there is room for every species.
+	expected = append(expected, "end 1")
+	assert.Equal(t, expected, reporter.events)
+
+	reporter.events = nil
+	_, err = neat.EvaluateGeneration(pop, constantEvaluator(1))
+	assert.NoError(t, err)
+	assert.Equal(t, "start", reporter.events[0])
+	assert.Equal(t, "evaluated", reporter.events[1])
+	assert.NotContains(t, reporter.events, "best 1", "fitness didn't improve")
+	assert.Equal(t, "end 2", reporter.events[len(reporter.events)-1])
+}
+
+func TestReporter_Extinction(t *testing.T) {
+	reporter := &recordingReporter{}
+	cfg := extinctionConfig()
+	cfg.PopulationSize = 4
+	cfg.SpeciesCompatThreshold = 1000
+	cfg.ResetOnExtinction = true
+	cfg.Reporters = []neat.Reporter{reporter}
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	_, err = runGenerationWithFitness(pop, func(i int) float64 {
+		return float64(i)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"start",
+		"evaluated",
+		"best 3",
+		"created 1",
+		"stagnated 1",
+		"extinct 1",
+		"extinction",
+		"end 1",
+	}, reporter.events)
+}
+
+func TestTextReporter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	cfg.Reporters = []neat.Reporter{neat.NewTextReporter(buf)}
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	_, err = neat.EvaluateGeneration(pop, constantEvaluator(2))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(buf.String(), "Generation 1\nBestFitness: 2.000000\n"), buf.String())
+	assert.Contains(t, buf.String(), "PopSize: 10\n")
+}
+
+func TestJSONLinesReporter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	reporter := neat.NewJSONLinesReporter(buf)
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	cfg.Reporters = []neat.Reporter{reporter}
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	_, err = neat.EvaluateGeneration(pop, constantEvaluator(2))
+	assert.NoError(t, err)
+	assert.NoError(t, reporter.Err())
+
+	events := make([]neat.ReportEvent, 0)
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		event := neat.ReportEvent{}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	assert.Equal(t, "generation-start", events[0].Event)
+	// Nothing has been evaluated yet, so there is no best fitness.
+	assert.Nil(t, events[0].BestFitness)
+	assert.Equal(t, "post-evaluation", events[1].Event)
+	assert.Equal(t, "new-best-genome", events[2].Event)
+	assert.Equal(t, 2., *events[2].Fitness)
+	assert.Equal(t, "species-created", events[3].Event)
+	assert.NotZero(t, events[3].SpeciesID)
+
+	last := events[len(events)-1]
+	assert.Equal(t, "generation-end", last.Event)
+	assert.Equal(t, 1, last.Generation)
+	assert.Equal(t, 2., *last.BestFitness)
+	assert.Equal(t, 10, last.NumGenomes)
+}
@@ -0,0 +1,130 @@
+package neat
+
+import (
+	"context"
+	"time"
+)
+
+type StopReason string
+
+const (
+	StopFitnessReached StopReason = "fitness-reached"
+	StopMaxGenerations StopReason = "max-generations"
+	StopTimeLimit      StopReason = "time-limit"
+	StopNoImprovement  StopReason = "no-improvement"
+	StopCancelled      StopReason = "cancelled"
+	StopError          StopReason = "error"
+)
+
+// RunStatus describes the progress of Run after a generation.
+type RunStatus struct {
+	Population Population
+	// Generations is the number of generations run so far by this call to Run.
+	Generations int
+	Elapsed     time.Duration
+	// GenerationsWithoutImprovement is the number of generations since BestEverGenomeFitness last improved.
+	GenerationsWithoutImprovement int
+}
+
+// StopCondition stops Run with Reason once ShouldStop returns true.
+type StopCondition struct {
+	Reason     StopReason
+	ShouldStop func(status RunStatus) bool
+}
+
+// FitnessReached stops once a generation's champion reaches threshold.
+func FitnessReached(threshold float64) StopCondition {
+	return StopWhen(StopFitnessReached, func(status RunStatus) bool {
+		return status.Population.BestGenomeFitness >= threshold
+	})
+}
+
+// MaxGenerations stops after n generations.
+func MaxGenerations(n int) StopCondition {
+	return StopWhen(StopMaxGenerations, func(status RunStatus) bool {
+		return status.Generations >= n
+	})
+}
+
+// TimeLimit stops after the first generation to finish once limit has passed.
+func TimeLimit(limit time.Duration) StopCondition {
+	return StopWhen(StopTimeLimit, func(status RunStatus) bool {
+		return status.Elapsed >= limit
+	})
+}
+
+// NoImprovement stops once the best ever fitness hasn't improved for n generations.
+func NoImprovement(n int) StopCondition {
+	return StopWhen(StopNoImprovement, func(status RunStatus) bool {
+		return status.GenerationsWithoutImprovement >= n
+	})
+}
+
+// StopWhen stops with reason once shouldStop returns true.
+func StopWhen(reason StopReason, shouldStop func(status RunStatus) bool) StopCondition {
+	return StopCondition{
+		Reason:     reason,
+		ShouldStop: shouldStop,
+	}
+}
+
+type RunOptions struct {
+	// StopConditions are checked in order after every generation, and the first that is met stops the run.
+	StopConditions []StopCondition
+	// OnGeneration is called after every generation, before the stop conditions are checked.
+	OnGeneration func(status RunStatus)
+}
+
+// RunSummary describes why and when Run stopped.
+type RunSummary struct {
+	Reason      StopReason
+	Generations int
+	Elapsed     time.Duration
+	BestFitness float64
+	BestGenome  Genome
+}
+
+// Run evaluates generations of the population with evaluator until a stop condition is met or ctx is done, and returns
+// the final population and a summary. The context is checked between generations. If a generation fails, or ctx is
+// done, the summary has the reason StopError or StopCancelled, and the error is returned.
+func Run(ctx context.Context, pop Population, evaluator Evaluator, opts RunOptions) (Population, RunSummary, error) {
+	start := time.Now()
+	status := RunStatus{Population: pop}
+	summarise := func(reason StopReason) RunSummary {
+		return RunSummary{
+			Reason:      reason,
+			Generations: status.Generations,
+			Elapsed:     time.Since(start),
+			BestFitness: status.Population.BestEverGenomeFitness,
+			BestGenome:  status.Population.BestEverGenome,
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return status.Population, summarise(StopCancelled), err
+		}
+		bestFitness := status.Population.BestEverGenomeFitness
+		next, err := EvaluateGeneration(status.Population, evaluator)
+		status.Population = next
+		status.Generations++
+		status.Elapsed = time.Since(start)
+		if err != nil {
+			return status.Population, summarise(StopError), err
+		}
+		if status.Population.BestEverGenomeFitness > bestFitness {
+			status.GenerationsWithoutImprovement = 0
+		} else {
+			status.GenerationsWithoutImprovement++
+		}
+
+		if opts.OnGeneration != nil {
+			opts.OnGeneration(status)
+		}
+		for _, condition := range opts.StopConditions {
+			if condition.ShouldStop(status) {
+				return status.Population, summarise(condition.Reason), nil
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package neat_test
+
+import (
+	"context"
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func runTestPopulation(t *testing.T) neat.Population {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 20
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	return pop
+}
+
+// constantEvaluator gives every genome the same fitness.
+func constantEvaluator(fitness float64) neat.Evaluator {
+	return func(state neat.ClientGenomeState) {
+		close(state.SendInput())
+		state.SendFitness() <- fitness
+	}
+}
+
+func TestRun_MaxGenerations(t *testing.T) {
+	statuses := make([]neat.RunStatus, 0)
+	pop, summary, err := neat.Run(context.Background(), runTestPopulation(t), xorEvaluator, neat.RunOptions{
+		StopConditions: []neat.StopCondition{neat.FitnessReached(100), neat.MaxGenerations(3)},
+		OnGeneration: func(status neat.RunStatus) {
+			statuses = append(statuses, status)
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, neat.StopMaxGenerations, summary.Reason)
+	assert.Equal(t, 3, summary.Generations)
+	assert.Equal(t, 3, pop.Generation)
+	assert.Equal(t, pop.BestEverGenomeFitness, summary.BestFitness)
+	assert.Len(t, statuses, 3)
+	assert.Equal(t, 2, statuses[1].Generations)
+}
+
+func TestRun_FitnessReached(t *testing.T) {
+	_, summary, err := neat.Run(context.Background(), runTestPopulation(t), constantEvaluator(5), neat.RunOptions{
+		StopConditions: []neat.StopCondition{neat.MaxGenerations(10), neat.FitnessReached(5)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, neat.StopFitnessReached, summary.Reason)
+	assert.Equal(t, 1, summary.Generations)
+	assert.Equal(t, 5., summary.BestFitness)
+}
+
+func TestRun_NoImprovement(t *testing.T) {
+	_, summary, err := neat.Run(context.Background(), runTestPopulation(t), constantEvaluator(1), neat.RunOptions{
+		StopConditions: []neat.StopCondition{neat.NoImprovement(3), neat.MaxGenerations(10)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, neat.StopNoImprovement, summary.Reason)
+	// The first generation improves on no fitness at all.
+	assert.Equal(t, 4, summary.Generations)
+}
+
+func TestRun_TimeLimit(t *testing.T) {
+	slow := func(state neat.ClientGenomeState) {
+		time.Sleep(10 * time.Millisecond)
+		constantEvaluator(1)(state)
+	}
+	_, summary, err := neat.Run(context.Background(), runTestPopulation(t), slow, neat.RunOptions{
+		StopConditions: []neat.StopCondition{neat.TimeLimit(25 * time.Millisecond), neat.MaxGenerations(100)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, neat.StopTimeLimit, summary.Reason)
+	assert.GreaterOrEqual(t, summary.Elapsed, 25*time.Millisecond)
+	assert.Less(t, summary.Generations, 100)
+}
+
+func TestRun_Custom(t *testing.T) {
+	_, summary, err := neat.Run(context.Background(), runTestPopulation(t), constantEvaluator(1), neat.RunOptions{
+		StopConditions: []neat.StopCondition{neat.StopWhen("two", func(status neat.RunStatus) bool {
+			return status.Population.Generation == 2
+		})},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, neat.StopReason("two"), summary.Reason)
+	assert.Equal(t, 2, summary.Generations)
+}
+
+func TestRun_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	_, summary, err := neat.Run(ctx, runTestPopulation(t), constantEvaluator(1), neat.RunOptions{
+		OnGeneration: func(status neat.RunStatus) {
+			if status.Generations == 2 {
+				cancel()
+			}
+		},
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, neat.StopCancelled, summary.Reason)
+	assert.Equal(t, 2, summary.Generations)
+}
+
+func TestRun_Error(t *testing.T) {
+	pop := runTestPopulation(t)
+	pop.Cfg.SpeciesStalenessThreshold = 1
+	pop.Cfg.SpeciesElitism = 0
+	_, summary, err := neat.Run(context.Background(), pop, constantEvaluator(1), neat.RunOptions{
+		StopConditions: []neat.StopCondition{neat.MaxGenerations(20)},
+	})
+	assert.ErrorIs(t, err, neat.ErrPopulationExtinct)
+	assert.Equal(t, neat.StopError, summary.Reason)
+}
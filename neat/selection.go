@@ -0,0 +1,296 @@
+package neat
+
+import (
+	"github.com/jmwri/neatgo/util"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Selector chooses which genomes become parents.
+type Selector interface {
+	// Select returns n genome indices chosen from candidates. The same genome may be chosen more than once.
+	Select(pop Population, candidates []int, n int) []int
+}
+
+// TournamentSelector picks the fittest of Size randomly chosen candidates for each parent.
+type TournamentSelector struct {
+	Size int
+}
+
+func (s TournamentSelector) Select(pop Population, candidates []int, n int) []int {
+	chosen := make([]int, 0, n)
+	if len(candidates) == 0 {
+		return chosen
+	}
+	size := s.Size
+	if size < 1 {
+		size = 1
+	}
+	for i := 0; i < n; i++ {
+		best := util.RandSliceElement(candidates)
+		for j := 1; j < size; j++ {
+			challenger := util.RandSliceElement(candidates)
+			if pop.GenomeFitness[challenger] > pop.GenomeFitness[best] {
+				best = challenger
+			}
+		}
+		chosen = append(chosen, best)
+	}
+	return chosen
+}
+
+// RankSelector uses linear ranking, where the chance of being chosen depends only on a candidate's position when
+// sorted by fitness. Pressure is between 1 and 2, where 1 chooses uniformly and 2 makes the fittest candidate twice as
+// likely as average to be chosen and the least fit never chosen.
+type RankSelector struct {
+	Pressure float64
+}
+
+func (s RankSelector) Select(pop Population, candidates []int, n int) []int {
+	if len(candidates) < 2 {
+		return RouletteSelector{}.Select(pop, candidates, n)
+	}
+	pressure := math.Max(1, math.Min(2, s.Pressure))
+	ranked := sortByFitness(pop, candidates)
+	numCandidates := float64(len(ranked))
+	weights := make([]float64, len(ranked))
+	for i := 0; i < len(ranked); {
+		// Candidates with equal fitness share the average of their ranks.
+		j := i + 1
+		for j < len(ranked) && fitnessEqual(pop.GenomeFitness[ranked[j]], pop.GenomeFitness[ranked[i]]) {
+			j++
+		}
+		// ranked is in descending order of fitness, so the least fit candidate has rank 0.
+		rank := numCandidates - 1 - float64(i+j-1)/2
+		for ; i < j; i++ {
+			weights[i] = (2-pressure)/numCandidates + 2*rank*(pressure-1)/(numCandidates*(numCandidates-1))
+		}
+	}
+	return weightedSelect(ranked, weights, n)
+}
+
+// RouletteSelector chooses candidates with a probability proportional to their fitness.
+// If any fitness is negative then all fitness is shifted so the least fit candidate has zero fitness, and if all
+// candidates have zero fitness then they are chosen uniformly.
+type RouletteSelector struct{}
+
+func (s RouletteSelector) Select(pop Population, candidates []int, n int) []int {
+	return weightedSelect(candidates, selectionWeights(pop, candidates), n)
+}
+
+// TruncationSelector chooses uniformly from the fittest Fraction of candidates.
+type TruncationSelector struct {
+	Fraction float64
+}
+
+func (s TruncationSelector) Select(pop Population, candidates []int, n int) []int {
+	chosen := make([]int, 0, n)
+	if len(candidates) == 0 {
+		return chosen
+	}
+	ranked := sortByFitness(pop, candidates)
+	keep := int(math.Ceil(s.Fraction * float64(len(ranked))))
+	if keep < 1 {
+		keep = 1
+	}
+	if keep > len(ranked) {
+		keep = len(ranked)
+	}
+	ranked = ranked[:keep]
+	for i := 0; i < n; i++ {
+		chosen = append(chosen, util.RandSliceElement(ranked))
+	}
+	return chosen
+}
+
+// StochasticUniversalSelector chooses all n candidates with a single spin of a roulette wheel with n evenly spaced
+// pointers, so the number of times a candidate is chosen stays close to its expected value.
+// Fitness is weighted in the same way as RouletteSelector. Evolve chooses all the parents of a species' offspring at
+// once, but GetOffspring chooses the parents of a single offspring, where this is little different to roulette.
+type StochasticUniversalSelector struct{}
+
+func (s StochasticUniversalSelector) Select(pop Population, candidates []int, n int) []int {
+	chosen := make([]int, 0, n)
+	if len(candidates) == 0 || n < 1 {
+		return chosen
+	}
+	weights := selectionWeights(pop, candidates)
+	weightSum := 0.0
+	for _, weight := range weights {
+		weightSum += weight
+	}
+	step := weightSum / float64(n)
+	pointer := util.FloatBetween(0, step)
+	pickSum := 0.0
+	i := 0
+	for len(chosen) < n {
+		pickSum += weights[i]
+		for pointer < pickSum && len(chosen) < n {
+			chosen = append(chosen, candidates[i])
+			pointer += step
+		}
+		i++
+		// Guard against floating point error leaving pointers beyond the last candidate.
+		if i == len(candidates) {
+			for len(chosen) < n {
+				chosen = append(chosen, candidates[len(candidates)-1])
+			}
+		}
+	}
+	return chosen
+}
+
+// LexicaseSelector chooses each parent by filtering candidates on the test cases in a random order, keeping only the
+// candidates with the lowest error on each case until one remains. Case errors are sent with SendCaseErrors.
+// Candidates within Epsilon of the lowest error on a case are also kept, which suits continuous errors. If
+// AutomaticEpsilon is set, the epsilon of each case is the median absolute deviation of the candidates' errors on it.
+// Candidates without case errors are only chosen if no candidate has any, in which case RouletteSelector is used.
+// NaN errors are treated as infinitely bad.
+// CullSpecies removes candidates by fitness before selection, so a high Cfg.SurvivalThreshold keeps more specialists.
+type LexicaseSelector struct {
+	Epsilon          float64
+	AutomaticEpsilon bool
+}
+
+func (s LexicaseSelector) Select(pop Population, candidates []int, n int) []int {
+	withErrors := make([]int, 0, len(candidates))
+	numCases := 0
+	for _, genomeIndex := range candidates {
+		if genomeIndex < len(pop.GenomeCaseErrors) && len(pop.GenomeCaseErrors[genomeIndex]) > 0 {
+			withErrors = append(withErrors, genomeIndex)
+			if cases := len(pop.GenomeCaseErrors[genomeIndex]); numCases == 0 || cases < numCases {
+				numCases = cases
+			}
+		}
+	}
+	if len(withErrors) == 0 {
+		return RouletteSelector{}.Select(pop, candidates, n)
+	}
+
+	epsilons := make([]float64, numCases)
+	for c := range epsilons {
+		epsilons[c] = s.Epsilon
+		if s.AutomaticEpsilon {
+			epsilons[c] = caseErrorMAD(pop, withErrors, c)
+		}
+	}
+
+	chosen := make([]int, 0, n)
+	for len(chosen) < n {
+		pool := withErrors
+		for _, c := range rand.Perm(numCases) {
+			if len(pool) == 1 {
+				break
+			}
+			lowest := math.Inf(1)
+			for _, genomeIndex := range pool {
+				lowest = math.Min(lowest, caseError(pop, genomeIndex, c))
+			}
+			survivors := make([]int, 0, len(pool))
+			for _, genomeIndex := range pool {
+				if caseError(pop, genomeIndex, c) <= lowest+epsilons[c] {
+					survivors = append(survivors, genomeIndex)
+				}
+			}
+			if len(survivors) > 0 {
+				pool = survivors
+			}
+		}
+		chosen = append(chosen, util.RandSliceElement(pool))
+	}
+	return chosen
+}
+
+// caseError returns the genome's error on case c, where NaN errors are infinite.
+func caseError(pop Population, genomeIndex int, c int) float64 {
+	value := pop.GenomeCaseErrors[genomeIndex][c]
+	if math.IsNaN(value) {
+		return math.Inf(1)
+	}
+	return value
+}
+
+// caseErrorMAD returns the median absolute deviation of the candidates' finite errors on case c.
+func caseErrorMAD(pop Population, candidates []int, c int) float64 {
+	errors := make([]float64, 0, len(candidates))
+	for _, genomeIndex := range candidates {
+		if value := caseError(pop, genomeIndex, c); !math.IsInf(value, 0) {
+			errors = append(errors, value)
+		}
+	}
+	median := MedianFitness(errors)
+	for i := range errors {
+		errors[i] = math.Abs(errors[i] - median)
+	}
+	return MedianFitness(errors)
+}
+
+// selectParents chooses n parents from candidates using the configured Selector.
+func selectParents(pop Population, candidates []int, n int) []int {
+	selector := pop.Cfg.ParentSelector
+	if selector == nil {
+		selector = RouletteSelector{}
+	}
+	return selector.Select(pop, candidates, n)
+}
+
+// selectionWeights returns a non-negative weight for each candidate based on its fitness.
+func selectionWeights(pop Population, candidates []int) []float64 {
+	minFitness := math.Inf(1)
+	for _, genomeIndex := range candidates {
+		minFitness = math.Min(minFitness, pop.GenomeFitness[genomeIndex])
+	}
+	shift := 0.0
+	if minFitness < 0 {
+		shift = -minFitness
+	}
+	weights := make([]float64, len(candidates))
+	weightSum := 0.0
+	for i, genomeIndex := range candidates {
+		weights[i] = pop.GenomeFitness[genomeIndex] + shift
+		weightSum += weights[i]
+	}
+	if weightSum <= 0 || math.IsNaN(weightSum) || math.IsInf(weightSum, 0) {
+		for i := range weights {
+			weights[i] = 1
+		}
+	}
+	return weights
+}
+
+// weightedSelect chooses n candidates with a probability proportional to their weight.
+func weightedSelect(candidates []int, weights []float64, n int) []int {
+	chosen := make([]int, 0, n)
+	if len(candidates) == 0 {
+		return chosen
+	}
+	weightSum := 0.0
+	for _, weight := range weights {
+		weightSum += weight
+	}
+	for len(chosen) < n {
+		chosenWeight := util.FloatBetween(0, weightSum)
+		pickSum := 0.0
+		pick := candidates[len(candidates)-1]
+		for i, genomeIndex := range candidates {
+			pickSum += weights[i]
+			if pickSum > chosenWeight {
+				pick = genomeIndex
+				break
+			}
+		}
+		chosen = append(chosen, pick)
+	}
+	return chosen
+}
+
+// sortByFitness returns a copy of candidates sorted in descending order of fitness, with NaN fitness last.
+func sortByFitness(pop Population, candidates []int) []int {
+	sorted := make([]int, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return fitnessLess(pop.GenomeFitness[sorted[j]], pop.GenomeFitness[sorted[i]])
+	})
+	return sorted
+}
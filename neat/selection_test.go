@@ -0,0 +1,235 @@
+package neat_test
+
+import (
+	"fmt"
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func selectionTestSelectors() []neat.Selector {
+	return []neat.Selector{
+		neat.TournamentSelector{Size: 3},
+		neat.RankSelector{Pressure: 2},
+		neat.RouletteSelector{},
+		neat.TruncationSelector{Fraction: .5},
+		neat.StochasticUniversalSelector{},
+		// Without case errors, lexicase falls back to roulette.
+		neat.LexicaseSelector{},
+	}
+}
+
+func TestSelectors_NegativeFitness(t *testing.T) {
+	pop := neat.Population{
+		GenomeFitness: []float64{-100, -50, -10, -1},
+	}
+	candidates := []int{0, 1, 2, 3}
+	for _, selector := range selectionTestSelectors() {
+		selector := selector
+		t.Run(fmt.Sprintf("%T", selector), func(t *testing.T) {
+			counts := make(map[int]int)
+			for i := 0; i < 200; i++ {
+				chosen := selector.Select(pop, candidates, 2)
+				assert.Len(t, chosen, 2)
+				for _, genomeIndex := range chosen {
+					assert.Contains(t, candidates, genomeIndex)
+					counts[genomeIndex]++
+				}
+			}
+			assert.Greater(t, counts[3], counts[0])
+		})
+	}
+}
+
+func TestSelectors_ZeroFitness(t *testing.T) {
+	pop := neat.Population{
+		GenomeFitness: []float64{0, 0, 0, 0},
+	}
+	candidates := []int{0, 1, 2, 3}
+	for _, selector := range selectionTestSelectors() {
+		selector := selector
+		if _, ok := selector.(neat.TruncationSelector); ok {
+			// Truncation only considers the first half when all fitness is equal.
+			continue
+		}
+		t.Run(fmt.Sprintf("%T", selector), func(t *testing.T) {
+			counts := make(map[int]int)
+			for i := 0; i < 200; i++ {
+				for _, genomeIndex := range selector.Select(pop, candidates, 2) {
+					counts[genomeIndex]++
+				}
+			}
+			assert.Len(t, counts, len(candidates))
+		})
+	}
+}
+
+func TestSelectors_NaNFitness(t *testing.T) {
+	pop := neat.Population{
+		GenomeFitness: []float64{1, math.NaN(), 3, math.NaN()},
+	}
+	candidates := []int{0, 1, 2, 3}
+	for _, selector := range selectionTestSelectors() {
+		selector := selector
+		t.Run(fmt.Sprintf("%T", selector), func(t *testing.T) {
+			for i := 0; i < 50; i++ {
+				chosen := selector.Select(pop, candidates, 2)
+				assert.Len(t, chosen, 2)
+				for _, genomeIndex := range chosen {
+					assert.Contains(t, candidates, genomeIndex)
+				}
+			}
+		})
+	}
+
+	// NaN fitness ranks lowest.
+	counts := make(map[int]int)
+	for _, genomeIndex := range (neat.RankSelector{Pressure: 2}).Select(pop, candidates, 600) {
+		counts[genomeIndex]++
+	}
+	assert.Greater(t, counts[0], counts[1])
+	assert.Greater(t, counts[0], counts[3])
+}
+
+func TestStochasticUniversalSelector_Spread(t *testing.T) {
+	pop := neat.Population{
+		GenomeFitness: []float64{1, 1, 2},
+	}
+	chosen := neat.StochasticUniversalSelector{}.Select(pop, []int{0, 1, 2}, 4)
+	counts := make(map[int]int)
+	for _, genomeIndex := range chosen {
+		counts[genomeIndex]++
+	}
+	assert.Equal(t, map[int]int{0: 1, 1: 1, 2: 2}, counts)
+}
+
+func TestLexicaseSelector(t *testing.T) {
+	pop := neat.Population{
+		GenomeFitness: []float64{0, 0, 0, 0},
+		GenomeCaseErrors: [][]float64{
+			{0, 5, 5},
+			{5, 0, 5},
+			// Never the best on any case, so plain lexicase never picks it.
+			{1, 1, 6},
+			// No case errors.
+			nil,
+		},
+	}
+	candidates := []int{0, 1, 2, 3}
+
+	counts := make(map[int]int)
+	for _, genomeIndex := range (neat.LexicaseSelector{}).Select(pop, candidates, 300) {
+		counts[genomeIndex]++
+	}
+	assert.Greater(t, counts[0], 0)
+	assert.Greater(t, counts[1], 0)
+	assert.Zero(t, counts[2])
+	assert.Zero(t, counts[3])
+
+	counts = make(map[int]int)
+	for _, genomeIndex := range (neat.LexicaseSelector{Epsilon: 1}).Select(pop, candidates, 300) {
+		counts[genomeIndex]++
+	}
+	assert.Greater(t, counts[2], 0, "genome within epsilon of the best should be chosen")
+	assert.Zero(t, counts[3])
+
+	counts = make(map[int]int)
+	for _, genomeIndex := range (neat.LexicaseSelector{AutomaticEpsilon: true}).Select(pop, candidates, 300) {
+		counts[genomeIndex]++
+	}
+	// The median absolute deviation of the first two cases is 1.
+	assert.Greater(t, counts[2], 0)
+}
+
+func TestLexicaseSelector_NaNErrors(t *testing.T) {
+	pop := neat.Population{
+		GenomeFitness: []float64{0, 0, 0},
+		GenomeCaseErrors: [][]float64{
+			{math.NaN(), 1},
+			{2, math.NaN()},
+			{math.NaN(), math.NaN()},
+		},
+	}
+	candidates := []int{0, 1, 2}
+	for _, selector := range []neat.LexicaseSelector{{}, {Epsilon: 1}, {AutomaticEpsilon: true}} {
+		counts := make(map[int]int)
+		for _, genomeIndex := range selector.Select(pop, candidates, 100) {
+			counts[genomeIndex]++
+		}
+		// NaN is the worst error, so the genome with only NaN errors is never chosen.
+		assert.Greater(t, counts[0], 0)
+		assert.Greater(t, counts[1], 0)
+		assert.Zero(t, counts[2])
+	}
+
+	pop.GenomeCaseErrors = [][]float64{{math.NaN()}, {math.NaN()}, {math.NaN()}}
+	assert.Len(t, (neat.LexicaseSelector{AutomaticEpsilon: true}).Select(pop, candidates, 10), 10)
+}
+
+func TestRunGeneration_CaseErrors(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 20
+	cfg.ParentSelector = neat.LexicaseSelector{AutomaticEpsilon: true}
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	cases := [][]float64{{0, 0}, {0, 1}, {1, 0}, {1, 1}}
+	for generation := 0; generation < 5; generation++ {
+		for _, state := range pop.States() {
+			go func(state neat.ClientGenomeState) {
+				caseErrors := make([]float64, len(cases))
+				totalError := 0.0
+				for i, input := range cases {
+					state.SendInput() <- input
+					output := <-state.GetOutput()
+					expected := 0.0
+					if input[0] != input[1] {
+						expected = 1
+					}
+					caseErrors[i] = math.Abs(output[0] - expected)
+					totalError += caseErrors[i]
+				}
+				close(state.SendInput())
+				state.SendCaseErrors() <- caseErrors
+				state.SendFitness() <- 4 - totalError
+			}(state)
+		}
+		pop, err = neat.RunGeneration(pop)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 5, pop.Generation)
+	assertSpeciesCoverPopulation(t, pop)
+}
+
+type countingSelector struct {
+	requests *[]int
+}
+
+func (s countingSelector) Select(pop neat.Population, candidates []int, n int) []int {
+	*s.requests = append(*s.requests, n)
+	return neat.RouletteSelector{}.Select(pop, candidates, n)
+}
+
+func TestEvolve_SelectsSpeciesParentsTogether(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	cfg.SpeciesCompatThreshold = 1000
+	cfg.Elitism = 1
+	requests := make([]int, 0)
+	cfg.ParentSelector = countingSelector{requests: &requests}
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	for i := range pop.GenomeFitness {
+		pop.GenomeFitness[i] = float64(i)
+	}
+
+	pop = neat.Speciate(pop)
+	pop = neat.RankSpecies(pop)
+	pop = neat.Evolve(pop)
+	assert.Len(t, pop.Genomes, 10)
+	// Every offspring needs one or two parents, all chosen in one call.
+	assert.Len(t, requests, 1)
+	assert.GreaterOrEqual(t, requests[0], 9)
+	assert.LessOrEqual(t, requests[0], 18)
+}
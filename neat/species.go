@@ -3,13 +3,14 @@ package neat
 import (
 	"github.com/jmwri/neatgo/util"
 	"math"
+	"math/rand"
 	"sort"
 )
 
 func NewSpecies(representative Genome) Species {
 	return Species{
 		AvgFitness:     .0,
-		BestFitness:    .0,
+		BestFitness:    math.Inf(-1),
 		Genomes:        make([]int, 0),
 		Representative: representative,
 		Staleness:      0,
@@ -17,6 +18,11 @@ func NewSpecies(representative Genome) Species {
 }
 
 type Species struct {
+	// ID identifies the species across generations. It is assigned by Speciate when the species is created.
+	ID                int
+	CreatedGeneration int
+	// Age is the number of generations since the species was created.
+	Age            int
 	AvgFitness     float64
 	BestFitness    float64
 	Genomes        []int
@@ -24,8 +30,30 @@ type Species struct {
 	Staleness      int
 }
 
+// SpeciesRecord is a snapshot of a species at the end of speciation in a generation.
+type SpeciesRecord struct {
+	Generation  int
+	Size        int
+	AvgFitness  float64
+	BestFitness float64
+}
+
 func Speciate(pop Population) Population {
+	return speciate(pop, pop.GenomeFitness)
+}
+
+// speciate is Speciate with species statistics, staleness and history measured on fitness, so that NextGeneration can
+// keep them in terms of raw fitness while offspring allocation and selection use shaped fitness.
+func speciate(pop Population, fitness []float64) Population {
+	// Every genome is compared with many representatives, so fingerprint each once rather than on every comparison.
+	fingerprints := make([]uint64, len(pop.Genomes))
+	if pop.DistanceCache != nil {
+		for i, genome := range pop.Genomes {
+			fingerprints[i] = genome.Fingerprint()
+		}
+	}
 	newSpecies := make([]Species, 0)
+	representativeFingerprints := make([]uint64, 0)
 	for i, species := range pop.Species {
 		if len(species.Genomes) == 0 {
 			// Remove extinct species
@@ -37,11 +65,12 @@ func Speciate(pop Population) Population {
 		// Remove all members from species
 		species.Genomes = make([]int, 0)
 		newSpecies = append(newSpecies, species)
+		representativeFingerprints = append(representativeFingerprints, fingerprints[newRepresentativeIndex])
 	}
 	for i, genome := range pop.Genomes {
 		foundSpecies := false
 		for j, species := range newSpecies {
-			if CompatibleWithSpecies(pop, species, genome) {
+			if compatibleWithSpecies(pop, species, representativeFingerprints[j], genome, fingerprints[i]) {
 				newSpecies[j].Genomes = append(newSpecies[j].Genomes, i)
 				foundSpecies = true
 				break
@@ -49,23 +78,29 @@ func Speciate(pop Population) Population {
 		}
 		if !foundSpecies {
 			species := NewSpecies(genome)
+			pop.LastSpeciesID++
+			species.ID = pop.LastSpeciesID
+			species.CreatedGeneration = pop.Generation
 			species.Genomes = append(species.Genomes, i)
 			newSpecies = append(newSpecies, species)
+			representativeFingerprints = append(representativeFingerprints, fingerprints[i])
 		}
 	}
-	extinctSpeciesIndices := make([]int, 0)
+	if pop.SpeciesHistory == nil {
+		pop.SpeciesHistory = make(map[int][]SpeciesRecord)
+	}
+	aliveSpecies := make([]Species, 0, len(newSpecies))
 	for i, species := range newSpecies {
 		if len(species.Genomes) == 0 {
 			// Remove extinct species
-			extinctSpeciesIndices = append(extinctSpeciesIndices, i)
 			continue
 		}
 		oldBestFitness := species.BestFitness
 		//oldAvgFitness := species.AvgFitness
-		bestFitness := 0.0
+		bestFitness := math.Inf(-1)
 		totalFitness := 0.0
 		for _, genome := range species.Genomes {
-			genomeFitness := pop.GenomeFitness[genome]
+			genomeFitness := fitness[genome]
 			totalFitness += genomeFitness
 			if genomeFitness > bestFitness {
 				bestFitness = genomeFitness
@@ -83,11 +118,33 @@ func Speciate(pop Population) Population {
 		} else {
 			newSpecies[i].Staleness = 0
 		}
+		newSpecies[i].Age = pop.Generation - newSpecies[i].CreatedGeneration
+
+		pop.SpeciesHistory[species.ID] = append(pop.SpeciesHistory[species.ID], SpeciesRecord{
+			Generation:  pop.Generation,
+			Size:        len(species.Genomes),
+			AvgFitness:  newSpecies[i].AvgFitness,
+			BestFitness: newSpecies[i].BestFitness,
+		})
+		aliveSpecies = append(aliveSpecies, newSpecies[i])
 	}
-	for i := len(extinctSpeciesIndices) - 1; i >= 0; i-- {
-		newSpecies = util.RemoveSliceIndex(newSpecies, i)
+	pop.Species = aliveSpecies
+	return adjustSpeciesCompatThreshold(pop)
+}
+
+// adjustSpeciesCompatThreshold moves the compatibility threshold towards one that produces Cfg.TargetSpeciesCount species.
+// Too many species raises the threshold so more genomes are compatible, too few lowers it.
+func adjustSpeciesCompatThreshold(pop Population) Population {
+	if pop.Cfg.TargetSpeciesCount < 1 {
+		return pop
+	}
+	threshold := pop.SpeciesCompatThreshold
+	if len(pop.Species) > pop.Cfg.TargetSpeciesCount {
+		threshold += pop.Cfg.SpeciesCompatThresholdStep
+	} else if len(pop.Species) < pop.Cfg.TargetSpeciesCount {
+		threshold -= pop.Cfg.SpeciesCompatThresholdStep
 	}
-	pop.Species = newSpecies
+	pop.SpeciesCompatThreshold = math.Max(pop.Cfg.MinSpeciesCompatThreshold, math.Min(pop.Cfg.MaxSpeciesCompatThreshold, threshold))
 	return pop
 }
 
@@ -123,9 +180,11 @@ func CullSpecies(pop Population) Population {
 
 func FitnessSharing(pop Population) Population {
 	for i, species := range pop.Species {
+		ageMultiplier := speciesAgeMultiplier(pop.Cfg, species)
 		fitnessSum := 0.0
 		for _, genomeIndex := range species.Genomes {
 			pop.GenomeFitness[genomeIndex] = pop.GenomeFitness[genomeIndex] / float64(len(species.Genomes))
+			pop.GenomeFitness[genomeIndex] = scaleFitness(pop.GenomeFitness[genomeIndex], ageMultiplier)
 			fitnessSum += pop.GenomeFitness[genomeIndex]
 		}
 		pop.Species[i].AvgFitness = fitnessSum / float64(len(species.Genomes))
@@ -133,6 +192,27 @@ func FitnessSharing(pop Population) Population {
 	return pop
 }
 
+// speciesAgeMultiplier returns how much to scale the fitness of a species by, protecting young species so that new
+// structure has time to optimise, and penalising old species.
+func speciesAgeMultiplier(cfg Config, species Species) float64 {
+	if cfg.SpeciesYoungAgeThreshold > 0 && species.Age < cfg.SpeciesYoungAgeThreshold {
+		return cfg.SpeciesYoungFitnessBoost
+	}
+	if cfg.SpeciesOldAgeThreshold > 0 && species.Age > cfg.SpeciesOldAgeThreshold {
+		return cfg.SpeciesOldFitnessPenalty
+	}
+	return 1
+}
+
+// scaleFitness multiplies positive fitness by the multiplier and divides negative fitness by it, so that a multiplier
+// above 1 always improves fitness and one below 1 always worsens it.
+func scaleFitness(fitness, multiplier float64) float64 {
+	if fitness < 0 {
+		return fitness / multiplier
+	}
+	return fitness * multiplier
+}
+
 func KillStaleSpecies(pop Population) Population {
 	keepSpecies := make([]Species, 0)
 	removedSpecies := make([]Species, 0)
@@ -154,7 +234,7 @@ func KillStaleSpecies(pop Population) Population {
 }
 
 func KillBadSpecies(pop Population) Population {
-	desiredOffspring := getDesiredOffspringCount(pop)
+	shares := getOffspringShares(pop)
 	keepSpecies := make([]Species, 0)
 	for i, species := range pop.Species {
 		if i < pop.Cfg.SpeciesElitism {
@@ -162,11 +242,8 @@ func KillBadSpecies(pop Population) Population {
 			keepSpecies = append(keepSpecies, species)
 			continue
 		}
-		numOffspring, ok := desiredOffspring[i]
-		if !ok {
-			continue
-		}
-		if numOffspring < pop.Cfg.MinSpeciesSize {
+		// Remove species that wouldn't earn the minimum number of offspring on their own fitness.
+		if int(math.Floor(shares[i])) < pop.Cfg.MinSpeciesSize {
 			continue
 		}
 		keepSpecies = append(keepSpecies, species)
@@ -177,156 +254,169 @@ func KillBadSpecies(pop Population) Population {
 	return pop
 }
 
-func getDesiredOffspringCount(pop Population) map[int]int {
+// getOffspringShares returns each species' share of Cfg.PopulationSize, proportional to its average fitness.
+func getOffspringShares(pop Population) []float64 {
 	avgFitnessSum := 0.0
 	for _, species := range pop.Species {
 		avgFitnessSum += species.AvgFitness
 	}
 
-	desiredOffspring := make(map[int]int)
-	for i, species := range pop.Species {
-		offspringCount := int(math.Floor(species.AvgFitness / avgFitnessSum * float64(pop.Cfg.PopulationSize)))
-		desiredOffspring[i] = offspringCount
+	shares := make([]float64, len(pop.Species))
+	if math.IsInf(avgFitnessSum, 1) || math.IsNaN(avgFitnessSum) {
+		return infiniteOffspringShares(pop)
 	}
-	return desiredOffspring
-}
-
-func CompatibleWithSpecies(pop Population, species Species, genome Genome) bool {
-	excessAndDisjoint := countExcessAndDisjointGenes(genome, species.Representative)
-	averageWeightDiff := calculateAverageConnectionWeightDiff(genome, species.Representative)
-	averageBiasDiff := calculateAverageNodeBiasDiff(genome, species.Representative)
-
-	var largeGenomeNormaliser = (genome.NumLayers() + genome.NumNodes()) - 20
-	if largeGenomeNormaliser < 1 {
-		largeGenomeNormaliser = 1
+	for i, species := range pop.Species {
+		share := species.AvgFitness / avgFitnessSum
+		// Fitness should be normalised to be non-negative, but if every species has no fitness then share equally.
+		if avgFitnessSum <= 0 {
+			share = 1 / float64(len(pop.Species))
+		}
+		shares[i] = share * float64(pop.Cfg.PopulationSize)
 	}
-
-	excessAndDisjointDiff := pop.Cfg.SpeciesCompatExcessCoeff * float64(excessAndDisjoint) / float64(largeGenomeNormaliser)
-	weightDiff := pop.Cfg.SpeciesCompatWeightDiffCoeff * averageWeightDiff
-	biasDiff := pop.Cfg.SpeciesCompatBiasDiffCoeff * averageBiasDiff
-
-	// Lower means more similar
-	compatibility := excessAndDisjointDiff + weightDiff + biasDiff
-	return compatibility <= pop.Cfg.SpeciesCompatThreshold
+	return shares
 }
 
-func countExcessAndDisjointGenes(a, b Genome) int {
-	innovationNumCount := make(map[int]int)
-
-	for _, node := range a.Layers.Nodes() {
-		innovationNumCount[node.ID]++
-	}
-	for _, node := range b.Layers.Nodes() {
-		innovationNumCount[node.ID]++
-	}
-	for _, connection := range a.Connections {
-		innovationNumCount[connection.ID]++
-	}
-	for _, connection := range b.Connections {
-		innovationNumCount[connection.ID]++
+// infiniteOffspringShares shares the population between species when their fitness can't be summed. Species with
+// infinite average fitness share it equally, or every species does if none are infinite.
+func infiniteOffspringShares(pop Population) []float64 {
+	numInfinite := 0
+	for _, species := range pop.Species {
+		if math.IsInf(species.AvgFitness, 1) {
+			numInfinite++
+		}
 	}
-
-	tot := 0
-	for _, count := range innovationNumCount {
-		if count < 2 {
-			tot++
+	shares := make([]float64, len(pop.Species))
+	for i, species := range pop.Species {
+		if numInfinite == 0 {
+			shares[i] = float64(pop.Cfg.PopulationSize) / float64(len(pop.Species))
+		} else if math.IsInf(species.AvgFitness, 1) {
+			shares[i] = float64(pop.Cfg.PopulationSize) / float64(numInfinite)
 		}
 	}
-
-	return tot
+	return shares
 }
 
-func calculateAverageConnectionWeightDiff(a, b Genome) float64 {
-	innovationNumCount := make(map[int]int)
-	innovationWeights := make(map[int]float64)
-	for _, connection := range a.Connections {
-		innovationNumCount[connection.ID]++
-		innovationWeights[connection.ID] = connection.Weight
-	}
-	for _, connection := range b.Connections {
-		innovationNumCount[connection.ID]++
-		innovationWeights[connection.ID] -= connection.Weight
+// getDesiredOffspringCount allocates exactly Cfg.PopulationSize offspring between species, keyed by species index.
+// Each species gets the whole part of its share, and the remaining offspring go to the species with the largest
+// fractional parts. Species below Cfg.MinSpeciesSize then take offspring from the largest species. If there are too
+// many species for every one to reach the minimum size, the highest ranked species are filled first.
+func getDesiredOffspringCount(pop Population) map[int]int {
+	shares := getOffspringShares(pop)
+	counts := make([]int, len(shares))
+	remaining := pop.Cfg.PopulationSize
+	for i, share := range shares {
+		counts[i] = int(math.Floor(share))
+		remaining -= counts[i]
 	}
 
-	tot := .0
-	totalWeightDiff := .0
-	for i, count := range innovationNumCount {
-		if count == 2 {
-			tot++
-			totalWeightDiff += math.Abs(innovationWeights[i])
-		}
+	// Largest remainder: give what's left to the species that lost the most to rounding down.
+	byRemainder := make([]int, len(shares))
+	for i := range byRemainder {
+		byRemainder[i] = i
 	}
-
-	// Avoid divide by zero
-	if tot == 0 {
-		return 100
+	sort.SliceStable(byRemainder, func(i, j int) bool {
+		a := shares[byRemainder[i]] - float64(counts[byRemainder[i]])
+		b := shares[byRemainder[j]] - float64(counts[byRemainder[j]])
+		return a > b
+	})
+	for i := 0; remaining > 0 && len(byRemainder) > 0; i++ {
+		counts[byRemainder[i%len(byRemainder)]]++
+		remaining--
 	}
-	return totalWeightDiff / tot
-}
 
-func calculateAverageNodeBiasDiff(a, b Genome) float64 {
-	innovationNumCount := make(map[int]int)
-	innovationBiases := make(map[int]float64)
-	for _, layer := range a.Layers {
-		for _, node := range layer {
-			innovationNumCount[node.ID]++
-			innovationBiases[node.ID] = node.Bias
+	minSize := pop.Cfg.MinSpeciesSize
+	if minSize*len(counts) > pop.Cfg.PopulationSize {
+		// Not every species can be kept, so keep as many of the top species as possible.
+		remaining = pop.Cfg.PopulationSize
+		for i := range counts {
+			counts[i] = 0
+			if remaining >= minSize {
+				counts[i] = minSize
+				remaining -= minSize
+			}
 		}
-	}
-	for _, layer := range b.Layers {
-		for _, node := range layer {
-			innovationNumCount[node.ID]++
-			innovationBiases[node.ID] -= node.Bias
+		for i := 0; remaining > 0 && len(counts) > 0; i++ {
+			counts[i%len(counts)]++
+			remaining--
 		}
-	}
-
-	tot := .0
-	totalBiasDiff := .0
-	for i, count := range innovationNumCount {
-		if count == 2 {
-			tot++
-			totalBiasDiff += math.Abs(innovationBiases[i])
+	} else {
+		for i := range counts {
+			for counts[i] < minSize {
+				// Take an offspring from the largest species that can spare one.
+				largest := -1
+				for j := range counts {
+					if counts[j] > minSize && (largest == -1 || counts[j] > counts[largest]) {
+						largest = j
+					}
+				}
+				if largest == -1 {
+					break
+				}
+				counts[largest]--
+				counts[i]++
+			}
 		}
 	}
 
-	// Avoid divide by zero
-	if tot == 0 {
-		return 100
+	desiredOffspring := make(map[int]int)
+	for i, count := range counts {
+		desiredOffspring[i] = count
 	}
-	return totalBiasDiff / tot
+	return desiredOffspring
+}
+
+func CompatibleWithSpecies(pop Population, species Species, genome Genome) bool {
+	// Lower means more similar
+	compatibility := genomeDistance(pop, genome, species.Representative)
+	return compatibility <= pop.SpeciesCompatThreshold
+}
+
+// compatibleWithSpecies is CompatibleWithSpecies for a genome and representative whose fingerprints are already known.
+func compatibleWithSpecies(pop Population, species Species, representativeFingerprint uint64, genome Genome, genomeFingerprint uint64) bool {
+	compatibility := fingerprintedDistance(pop, genome, genomeFingerprint, species.Representative, representativeFingerprint)
+	return compatibility <= pop.SpeciesCompatThreshold
 }
 
 func GetOffspring(pop Population, species Species) Genome {
-	performCrossover := util.FloatBetween(0, 1) < pop.Cfg.MateCrossoverRate
-	var baby Genome
-	if performCrossover {
-		a := getSpeciesGenomeForCrossover(pop, species)
-		b := getSpeciesGenomeForCrossover(pop, species)
-		if pop.GenomeFitness[a] < pop.GenomeFitness[b] {
-			a, b = b, a
-		}
-		aGenome := pop.Genomes[a]
-		bGenome := pop.Genomes[b]
-		baby = Crossover(pop.Cfg, aGenome, bGenome)
-	} else {
-		randomGenome := getSpeciesGenomeForCrossover(pop, species)
-		baby = CopyGenome(pop.Genomes[randomGenome])
-	}
-	return MutateGenome(pop.Cfg, baby)
+	return getOffspring(pop, species, 1)[0]
 }
 
-func getSpeciesGenomeForCrossover(pop Population, species Species) int {
-	fitnessSum := 0.0
-	for _, genomeID := range species.Genomes {
-		fitnessSum += pop.GenomeFitness[genomeID]
+// getOffspring breeds n offspring from the species. The parents of every offspring are chosen with a single call to the
+// Selector, so that selectors such as StochasticUniversalSelector spread their choices across the whole brood.
+func getOffspring(pop Population, species Species, n int) []Genome {
+	performCrossover := make([]bool, n)
+	numParents := 0
+	for i := range performCrossover {
+		performCrossover[i] = util.FloatBetween(0, 1) < pop.Cfg.MateCrossoverRate
+		numParents++
+		if performCrossover[i] {
+			numParents++
+		}
 	}
-	chosenFitness := util.FloatBetween(0, fitnessSum)
-	pickSum := 0.0
-	for _, genomeID := range species.Genomes {
-		pickSum += pop.GenomeFitness[genomeID]
-		if pickSum > chosenFitness {
-			return genomeID
+	parents := selectParents(pop, species.Genomes, numParents)
+	// Selectors may return parents in order, so shuffle them to avoid crossing a genome with itself.
+	rand.Shuffle(len(parents), func(i, j int) {
+		parents[i], parents[j] = parents[j], parents[i]
+	})
+
+	offspring := make([]Genome, n)
+	for i := range offspring {
+		var baby Genome
+		if performCrossover[i] {
+			a, b := parents[0], parents[1]
+			parents = parents[2:]
+			if pop.GenomeFitness[a] < pop.GenomeFitness[b] {
+				a, b = b, a
+			}
+			aGenome := pop.Genomes[a]
+			bGenome := pop.Genomes[b]
+			baby = Crossover(pop.Cfg, aGenome, bGenome)
+		} else {
+			randomGenome := parents[0]
+			parents = parents[1:]
+			baby = CopyGenome(pop.Genomes[randomGenome])
 		}
+		offspring[i] = MutateGenome(pop.Cfg, baby)
 	}
-	return species.Genomes[0]
+	return offspring
 }
@@ -0,0 +1,192 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSpeciate_TargetSpeciesCount(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	cfg.TargetSpeciesCount = 3
+	cfg.SpeciesCompatThreshold = 1
+	cfg.SpeciesCompatThresholdStep = .5
+	cfg.MaxSpeciesCompatThreshold = 2
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, pop.SpeciesCompatThreshold)
+
+	// Generated genomes share no genes, so each is its own species and the threshold should rise.
+	pop = neat.Speciate(pop)
+	assert.Len(t, pop.Species, 10)
+	assert.Equal(t, 1.5, pop.SpeciesCompatThreshold)
+	pop = neat.Speciate(pop)
+	assert.Equal(t, 2.0, pop.SpeciesCompatThreshold)
+	// Capped at the max threshold.
+	pop = neat.Speciate(pop)
+	assert.Equal(t, 2.0, pop.SpeciesCompatThreshold)
+}
+
+func TestSpeciate_TargetSpeciesCountTooFewSpecies(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	cfg.TargetSpeciesCount = 3
+	cfg.SpeciesCompatThreshold = 1000
+	cfg.MaxSpeciesCompatThreshold = 1000
+	cfg.SpeciesCompatThresholdStep = 10
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	pop = neat.Speciate(pop)
+	assert.Len(t, pop.Species, 1)
+	assert.Equal(t, 990.0, pop.SpeciesCompatThreshold)
+}
+
+func TestSpeciate_TargetSpeciesCountZeroThreshold(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	cfg.TargetSpeciesCount = 3
+	cfg.SpeciesCompatThreshold = 1000
+	cfg.SpeciesCompatThresholdStep = 1000
+	cfg.MinSpeciesCompatThreshold = 0
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	pop = neat.Speciate(pop)
+	assert.Equal(t, 0.0, pop.SpeciesCompatThreshold)
+	// A threshold of 0 is kept rather than treated as unset.
+	pop.Species = nil
+	pop = neat.Speciate(pop)
+	assert.Len(t, pop.Species, 10)
+}
+
+func TestSpeciate_FixedThreshold(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+
+	pop = neat.Speciate(pop)
+	assert.Equal(t, cfg.SpeciesCompatThreshold, pop.SpeciesCompatThreshold)
+}
+
+func TestSpeciate_SpeciesIdentity(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	cfg.PopulationSize = 10
+	pop, err := neat.GeneratePopulation(cfg)
+	assert.NoError(t, err)
+	for i := range pop.GenomeFitness {
+		pop.GenomeFitness[i] = float64(i)
+	}
+
+	pop.Generation = 1
+	pop = neat.Speciate(pop)
+	assert.Len(t, pop.Species, 10)
+	ids := make(map[int]bool)
+	for _, species := range pop.Species {
+		assert.False(t, ids[species.ID], "species ID %d is not unique", species.ID)
+		ids[species.ID] = true
+		assert.Equal(t, 1, species.CreatedGeneration)
+		assert.Equal(t, 0, species.Age)
+	}
+
+	pop = neat.RankSpecies(pop)
+	pop.Generation = 2
+	pop = neat.Speciate(pop)
+	assert.Len(t, pop.Species, 10)
+	for _, species := range pop.Species {
+		assert.True(t, ids[species.ID], "species ID %d should have been kept", species.ID)
+		assert.Equal(t, 1, species.Age)
+
+		history := pop.SpeciesHistory[species.ID]
+		assert.Len(t, history, 2)
+		assert.Equal(t, 1, history[0].Generation)
+		assert.Equal(t, 2, history[1].Generation)
+		assert.Equal(t, 1, history[1].Size)
+		assert.Equal(t, species.BestFitness, history[1].BestFitness)
+	}
+	assert.Equal(t, 10, pop.LastSpeciesID)
+}
+
+func TestFitnessSharing_SpeciesAge(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.SpeciesYoungAgeThreshold = 5
+	cfg.SpeciesYoungFitnessBoost = 2
+	cfg.SpeciesOldAgeThreshold = 20
+	cfg.SpeciesOldFitnessPenalty = .5
+	pop := neat.Population{
+		Cfg:           cfg,
+		GenomeFitness: []float64{4, 4, 4, 4, -4, -4},
+		Species: []neat.Species{
+			{Age: 1, Genomes: []int{0, 1}},
+			{Age: 10, Genomes: []int{2, 3}},
+			{Age: 30, Genomes: []int{4, 5}},
+		},
+	}
+
+	pop = neat.FitnessSharing(pop)
+	assert.Equal(t, []float64{4, 4, 2, 2, -4, -4}, pop.GenomeFitness)
+	assert.Equal(t, 4.0, pop.Species[0].AvgFitness)
+	assert.Equal(t, 2.0, pop.Species[1].AvgFitness)
+	assert.Equal(t, -4.0, pop.Species[2].AvgFitness)
+}
+
+func assertSpeciesCoverPopulation(t *testing.T, pop neat.Population) {
+	t.Helper()
+	assert.Len(t, pop.Genomes, pop.Cfg.PopulationSize)
+	assert.Len(t, pop.GenomeFitness, pop.Cfg.PopulationSize)
+	seen := make(map[int]int)
+	for _, species := range pop.Species {
+		for _, genomeIndex := range species.Genomes {
+			seen[genomeIndex]++
+		}
+	}
+	for i := range pop.Genomes {
+		assert.Equal(t, 1, seen[i], "genome %d should be in exactly one species", i)
+	}
+}
+
+func TestEvolve_ExactPopulationSize(t *testing.T) {
+	type testCase struct {
+		name           string
+		threshold      float64
+		minSpeciesSize int
+		elitism        int
+	}
+	tests := []testCase{
+		{name: "one species", threshold: 1000, minSpeciesSize: 1, elitism: 2},
+		{name: "elitism larger than species", threshold: 1000, minSpeciesSize: 1, elitism: 50},
+		{name: "species per genome", threshold: .1, minSpeciesSize: 1, elitism: 2},
+		{name: "too many species for min size", threshold: .1, minSpeciesSize: 3, elitism: 2},
+		{name: "min size", threshold: .1, minSpeciesSize: 2, elitism: 1},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			cfg := neat.DefaultConfig(2, 1)
+			cfg.PopulationSize = 23
+			cfg.SpeciesCompatThreshold = test.threshold
+			cfg.MinSpeciesSize = test.minSpeciesSize
+			cfg.Elitism = test.elitism
+			cfg.SpeciesElitism = 100
+			pop, err := neat.GeneratePopulation(cfg)
+			assert.NoError(t, err)
+
+			for generation := 0; generation < 3; generation++ {
+				for i := range pop.GenomeFitness {
+					pop.GenomeFitness[i] = float64(i % 7)
+				}
+				pop = neat.Speciate(pop)
+				pop = neat.RankSpecies(pop)
+				pop = neat.CullSpecies(pop)
+				pop = neat.NormaliseFitness(pop)
+				pop = neat.FitnessSharing(pop)
+				pop = neat.KillStaleSpecies(pop)
+				pop = neat.KillBadSpecies(pop)
+				pop = neat.Evolve(pop)
+				assertSpeciesCoverPopulation(t, pop)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package neat
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// TournamentMatch plays a game between a and b and returns their scores. Only the share of the total score each
+// genome gets matters, so a win can be 1, 0 and a draw 1, 1. Matches are played concurrently.
+type TournamentMatch func(a, b Genome) (scoreA, scoreB float64)
+
+type PairingStrategy string
+
+const (
+	// RoundRobinPairing plays every genome against every other genome once.
+	RoundRobinPairing PairingStrategy = "round-robin"
+	// SwissPairing plays genomes against genomes with a similar rating that they haven't already played, re-rating
+	// genomes after each round.
+	SwissPairing PairingStrategy = "swiss"
+	// RandomPairing plays every genome against a random genome each round.
+	RandomPairing PairingStrategy = "random"
+)
+
+type TournamentConfig struct {
+	Pairing       PairingStrategy // How to choose which genomes play each other.
+	Rounds        int             // How many rounds to play with Swiss and random pairing. Each genome plays once per round.
+	Workers       int             // How many matches to play at the same time. 0 uses the number of CPUs.
+	InitialRating float64         // The Elo rating of new genomes.
+	KFactor       float64         // The most a rating can change by in a single match.
+}
+
+func DefaultTournamentConfig() TournamentConfig {
+	return TournamentConfig{
+		Pairing:       SwissPairing,
+		Rounds:        5,
+		Workers:       0,
+		InitialRating: 1500,
+		KFactor:       32,
+	}
+}
+
+// Tournament evolves a population by playing its genomes against each other. The fitness of each genome is how far its
+// Elo rating is above the lowest rating in the generation, as ratings are close together compared to their size.
+type Tournament struct {
+	Cfg        TournamentConfig
+	Population Population
+	// Ratings contains the rating of each genome in the last generation, keyed by fingerprint, so that genomes that
+	// survive to the next generation keep their rating.
+	Ratings map[uint64]float64
+}
+
+func GenerateTournament(cfg TournamentConfig, popCfg Config) (Tournament, error) {
+	pop, err := GeneratePopulation(popCfg)
+	if err != nil {
+		return Tournament{}, err
+	}
+	return Tournament{
+		Cfg:        cfg,
+		Population: pop,
+		Ratings:    make(map[uint64]float64),
+	}, nil
+}
+
+// RunTournamentGeneration plays the tournament with match, sets the fitness of every genome to its rating minus the
+// lowest rating, and breeds the next generation with NextGeneration.
+func RunTournamentGeneration(t Tournament, match TournamentMatch) (Tournament, error) {
+	pop := t.Population
+	report(pop, func(r Reporter) {
+		r.GenerationStart(pop)
+	})
+	fingerprints := make([]uint64, len(pop.Genomes))
+	ratings := make([]float64, len(pop.Genomes))
+	for i, genome := range pop.Genomes {
+		fingerprints[i] = genome.Fingerprint()
+		rating, ok := t.Ratings[fingerprints[i]]
+		if !ok {
+			rating = t.Cfg.InitialRating
+		}
+		ratings[i] = rating
+	}
+
+	played := make(map[[2]int]bool)
+	switch t.Cfg.Pairing {
+	case RoundRobinPairing:
+		ratings = playTournamentRound(t.Cfg, pop.Genomes, ratings, roundRobinPairs(len(pop.Genomes)), match)
+	case SwissPairing, RandomPairing:
+		for round := 0; round < t.Cfg.Rounds; round++ {
+			var pairs [][2]int
+			if t.Cfg.Pairing == SwissPairing {
+				pairs = swissPairs(ratings, played)
+			} else {
+				pairs = randomPairs(len(pop.Genomes))
+			}
+			for _, pair := range pairs {
+				played[pair] = true
+				played[[2]int{pair[1], pair[0]}] = true
+			}
+			ratings = playTournamentRound(t.Cfg, pop.Genomes, ratings, pairs, match)
+		}
+	default:
+		return t, fmt.Errorf("unknown pairing strategy %q", t.Cfg.Pairing)
+	}
+
+	minRating := math.Inf(1)
+	for _, rating := range ratings {
+		minRating = math.Min(minRating, rating)
+	}
+	t.Ratings = make(map[uint64]float64, len(ratings))
+	for i, rating := range ratings {
+		pop.GenomeFitness[i] = rating - minRating
+		t.Ratings[fingerprints[i]] = rating
+	}
+
+	var err error
+	t.Population, err = NextGeneration(pop)
+	return t, err
+}
+
+// EloExpectedScore returns the expected share of the score for a player rated a against a player rated b.
+func EloExpectedScore(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// playTournamentRound plays every pair using Cfg.Workers workers, and returns the ratings after the round. Every match
+// in a round is rated using the ratings from the start of the round, so the result doesn't depend on the order that
+// matches finish in.
+func playTournamentRound(cfg TournamentConfig, genomes []Genome, ratings []float64, pairs [][2]int, match TournamentMatch) []float64 {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	deltas := make([][2]float64, len(pairs))
+	pairIndexes := make(chan int)
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for p := range pairIndexes {
+				a, b := pairs[p][0], pairs[p][1]
+				scoreA, scoreB := match(genomes[a], genomes[b])
+				actualA := .5
+				if total := scoreA + scoreB; total > 0 {
+					actualA = scoreA / total
+				}
+				delta := cfg.KFactor * (actualA - EloExpectedScore(ratings[a], ratings[b]))
+				deltas[p] = [2]float64{delta, -delta}
+			}
+		}()
+	}
+	for p := range pairs {
+		pairIndexes <- p
+	}
+	close(pairIndexes)
+	wg.Wait()
+
+	newRatings := append([]float64{}, ratings...)
+	for p, pair := range pairs {
+		newRatings[pair[0]] += deltas[p][0]
+		newRatings[pair[1]] += deltas[p][1]
+	}
+	return newRatings
+}
+
+func roundRobinPairs(n int) [][2]int {
+	pairs := make([][2]int, 0, n*(n-1)/2)
+	for a := 0; a < n; a++ {
+		for b := a + 1; b < n; b++ {
+			pairs = append(pairs, [2]int{a, b})
+		}
+	}
+	return pairs
+}
+
+// randomPairs pairs every genome with a random opponent. If there is an odd number of genomes, one doesn't play.
+func randomPairs(n int) [][2]int {
+	order := rand.Perm(n)
+	pairs := make([][2]int, 0, n/2)
+	for i := 0; i+1 < n; i += 2 {
+		pairs = append(pairs, [2]int{order[i], order[i+1]})
+	}
+	return pairs
+}
+
+// swissPairs pairs each genome with the highest rated genome below it that it hasn't played yet, backtracking if that
+// leaves genomes that can only be paired for a rematch. If there is no way to avoid rematches, each genome plays the
+// next highest rated genome that it hasn't played where possible. If there is an odd number of genomes, the lowest
+// rated genome doesn't play.
+func swissPairs(ratings []float64, played map[[2]int]bool) [][2]int {
+	order := make([]int, len(ratings))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return ratings[order[i]] > ratings[order[j]]
+	})
+	if len(order)%2 == 1 {
+		order = order[:len(order)-1]
+	}
+
+	// Limit the search, so a population where rematches can't be avoided doesn't take too long to pair.
+	budget := 10000
+	paired := make(map[int]bool)
+	pairs := make([][2]int, 0, len(order)/2)
+	var search func() bool
+	search = func() bool {
+		a := -1
+		for _, genome := range order {
+			if !paired[genome] {
+				a = genome
+				break
+			}
+		}
+		if a == -1 {
+			return true
+		}
+		paired[a] = true
+		for _, b := range order {
+			if paired[b] || played[[2]int{a, b}] {
+				continue
+			}
+			if budget--; budget < 0 {
+				break
+			}
+			paired[b] = true
+			pairs = append(pairs, [2]int{a, b})
+			if search() {
+				return true
+			}
+			pairs = pairs[:len(pairs)-1]
+			paired[b] = false
+		}
+		paired[a] = false
+		return false
+	}
+	if search() {
+		return pairs
+	}
+
+	paired = make(map[int]bool)
+	pairs = pairs[:0]
+	for i, a := range order {
+		if paired[a] {
+			continue
+		}
+		opponent := -1
+		for _, b := range order[i+1:] {
+			if paired[b] {
+				continue
+			}
+			if opponent == -1 {
+				opponent = b
+			}
+			if !played[[2]int{a, b}] {
+				opponent = b
+				break
+			}
+		}
+		paired[a] = true
+		paired[opponent] = true
+		pairs = append(pairs, [2]int{a, opponent})
+	}
+	return pairs
+}
@@ -0,0 +1,151 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"sync"
+	"testing"
+)
+
+func TestEloExpectedScore(t *testing.T) {
+	assert.InDelta(t, .5, neat.EloExpectedScore(1500, 1500), 1e-9)
+	assert.InDelta(t, 1/(1+0.1), neat.EloExpectedScore(1900, 1500), 1e-9)
+	assert.InDelta(t, 1, neat.EloExpectedScore(1500, 1900)+neat.EloExpectedScore(1900, 1500), 1e-9)
+}
+
+// matchRecorder plays higherOutputWins and records every pair of genomes that played.
+type matchRecorder struct {
+	mu      sync.Mutex
+	matches map[[2]uint64]int
+	total   int
+}
+
+func (r *matchRecorder) play(a, b neat.Genome) (float64, float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.matches == nil {
+		r.matches = make(map[[2]uint64]int)
+	}
+	r.matches[[2]uint64{a.Fingerprint(), b.Fingerprint()}]++
+	r.total++
+	scoreA := higherOutputWins(a, b)
+	return scoreA, 1 - scoreA
+}
+
+func TestRunTournamentGeneration_RoundRobin(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 10
+	tournament, err := neat.GenerateTournament(neat.TournamentConfig{
+		Pairing:       neat.RoundRobinPairing,
+		Workers:       3,
+		InitialRating: 1500,
+		KFactor:       32,
+	}, cfg)
+	assert.NoError(t, err)
+
+	recorder := &matchRecorder{}
+	genomes := tournament.Population.Genomes
+	tournament, err = neat.RunTournamentGeneration(tournament, recorder.play)
+	assert.NoError(t, err)
+	assert.Equal(t, 45, recorder.total)
+	assert.Equal(t, 1, tournament.Population.Generation)
+
+	// Ratings are zero sum.
+	total := 0.0
+	for _, genome := range genomes {
+		total += tournament.Ratings[genome.Fingerprint()]
+	}
+	assert.InDelta(t, 1500*10, total, 1e-6)
+	// Fitness is the rating above the lowest rating.
+	minRating := math.Inf(1)
+	for _, rating := range tournament.Ratings {
+		minRating = math.Min(minRating, rating)
+	}
+	assert.InDelta(t, maxRating(tournament.Ratings)-minRating, tournament.Population.BestEverGenomeFitness, 1e-9)
+	assert.Contains(t, tournament.Population.RawFitness, 0.)
+}
+
+func TestRunTournamentGeneration_Swiss(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 10
+	tournament, err := neat.GenerateTournament(neat.TournamentConfig{
+		Pairing:       neat.SwissPairing,
+		Rounds:        4,
+		InitialRating: 1500,
+		KFactor:       32,
+	}, cfg)
+	assert.NoError(t, err)
+
+	recorder := &matchRecorder{}
+	tournament, err = neat.RunTournamentGeneration(tournament, recorder.play)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, recorder.total)
+	for pair, count := range recorder.matches {
+		assert.Equal(t, 1, count, "genomes should not play twice")
+		assert.Zero(t, recorder.matches[[2]uint64{pair[1], pair[0]}])
+	}
+}
+
+func TestRunTournamentGeneration_Random(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 9
+	tournament, err := neat.GenerateTournament(neat.TournamentConfig{
+		Pairing:       neat.RandomPairing,
+		Rounds:        3,
+		InitialRating: 1500,
+		KFactor:       32,
+	}, cfg)
+	assert.NoError(t, err)
+
+	recorder := &matchRecorder{}
+	_, err = neat.RunTournamentGeneration(tournament, recorder.play)
+	assert.NoError(t, err)
+	// One genome sits out each round.
+	assert.Equal(t, 12, recorder.total)
+}
+
+func TestRunTournamentGeneration_RatingsCarryOver(t *testing.T) {
+	cfg := neat.DefaultConfig(1, 1)
+	cfg.PopulationSize = 10
+	cfg.Elitism = 2
+	tournament, err := neat.GenerateTournament(neat.TournamentConfig{
+		Pairing:       neat.RoundRobinPairing,
+		InitialRating: 1500,
+		KFactor:       32,
+	}, cfg)
+	assert.NoError(t, err)
+	recorder := &matchRecorder{}
+	tournament, err = neat.RunTournamentGeneration(tournament, recorder.play)
+	assert.NoError(t, err)
+	previous := tournament.Ratings
+
+	// With no rounds, every genome keeps the rating it starts with.
+	tournament.Cfg.Pairing = neat.SwissPairing
+	tournament.Cfg.Rounds = 0
+	survivors := 0
+	genomes := tournament.Population.Genomes
+	tournament, err = neat.RunTournamentGeneration(tournament, recorder.play)
+	assert.NoError(t, err)
+	for _, genome := range genomes {
+		fingerprint := genome.Fingerprint()
+		rating, ok := previous[fingerprint]
+		if !ok {
+			rating = 1500
+		} else {
+			survivors++
+		}
+		assert.Equal(t, rating, tournament.Ratings[fingerprint])
+	}
+	assert.Greater(t, survivors, 0, "elites should keep their rating")
+}
+
+func maxRating(ratings map[uint64]float64) float64 {
+	best := 0.0
+	for _, rating := range ratings {
+		if rating > best {
+			best = rating
+		}
+	}
+	return best
+}
@@ -0,0 +1,181 @@
+package neat
+
+import (
+	"fmt"
+	"github.com/jmwri/neatgo/network"
+)
+
+type GenomeProblemType string
+
+const (
+	DuplicateID         GenomeProblemType = "duplicate-id"
+	MissingNode         GenomeProblemType = "missing-node"
+	UnknownActivation   GenomeProblemType = "unknown-activation"
+	MisplacedNode       GenomeProblemType = "misplaced-node"
+	BackwardConnection  GenomeProblemType = "backward-connection"
+	CyclicConnection    GenomeProblemType = "cyclic-connection"
+	EmptyLayer          GenomeProblemType = "empty-layer"
+	InputCountMismatch  GenomeProblemType = "input-count-mismatch"
+	OutputCountMismatch GenomeProblemType = "output-count-mismatch"
+	TooFewLayers        GenomeProblemType = "too-few-layers"
+	UnknownNodeType     GenomeProblemType = "unknown-node-type"
+)
+
+// genomeProblemID is used as the GenomeProblem ID when a problem isn't caused by a single gene.
+const genomeProblemID = -1
+
+// GenomeProblem describes a single way in which a Genome is not well formed.
+// ID is the innovation ID of the offending node or connection, the layer index for EmptyLayer, or -1 when the problem
+// applies to the whole genome.
+type GenomeProblem struct {
+	Type    GenomeProblemType
+	ID      int
+	Message string
+}
+
+func (p GenomeProblem) Error() string {
+	return fmt.Sprintf("%s: %s", p.Type, p.Message)
+}
+
+// ValidateGenome checks that the genome can be activated as a feed-forward network matching cfg.Layers.
+// An empty slice means the genome is well formed.
+func ValidateGenome(cfg Config, genome Genome) []GenomeProblem {
+	problems := make([]GenomeProblem, 0)
+	addProblem := func(problemType GenomeProblemType, id int, format string, args ...any) {
+		problems = append(problems, GenomeProblem{
+			Type:    problemType,
+			ID:      id,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	if len(genome.Layers) < 2 {
+		addProblem(TooFewLayers, genomeProblemID, "genome has %d layers, needs at least an input and output layer", len(genome.Layers))
+	}
+
+	seenIDs := make(map[int]bool)
+	nodeLayers := make(map[int]int)
+	numInputs := 0
+	numOutputs := 0
+	lastLayer := len(genome.Layers) - 1
+	for i, layer := range genome.Layers {
+		if len(layer) == 0 {
+			addProblem(EmptyLayer, i, "layer %d has no nodes", i)
+		}
+		for _, node := range layer {
+			if seenIDs[node.ID] {
+				addProblem(DuplicateID, node.ID, "node %d is not unique", node.ID)
+				continue
+			}
+			seenIDs[node.ID] = true
+			nodeLayers[node.ID] = i
+
+			if network.ActivationRegistry.Get(node.ActivationFn) == nil {
+				addProblem(UnknownActivation, node.ID, "node %d has unknown activation function %q", node.ID, node.ActivationFn)
+			}
+
+			switch node.Type {
+			case network.Input, network.Bias:
+				if node.Type == network.Input {
+					numInputs++
+				}
+				if i != 0 {
+					addProblem(MisplacedNode, node.ID, "%s node %d is in layer %d, should be in the input layer", node.Type, node.ID, i)
+				}
+			case network.Output:
+				numOutputs++
+				if i != lastLayer {
+					addProblem(MisplacedNode, node.ID, "output node %d is in layer %d, should be in the output layer", node.ID, i)
+				}
+			case network.Hidden:
+				if i == 0 || i == lastLayer {
+					addProblem(MisplacedNode, node.ID, "hidden node %d is in layer %d, should be between the input and output layers", node.ID, i)
+				}
+			default:
+				addProblem(UnknownNodeType, node.ID, "node %d has unknown type %q", node.ID, node.Type)
+			}
+		}
+	}
+
+	if len(cfg.Layers) > 0 {
+		if expected := cfg.Layers[0]; numInputs != expected {
+			addProblem(InputCountMismatch, genomeProblemID, "genome has %d input nodes, config expects %d", numInputs, expected)
+		}
+		if expected := cfg.Layers[len(cfg.Layers)-1]; numOutputs != expected {
+			addProblem(OutputCountMismatch, genomeProblemID, "genome has %d output nodes, config expects %d", numOutputs, expected)
+		}
+	}
+
+	outgoing := make(map[int][]int)
+	for _, connection := range genome.Connections {
+		if seenIDs[connection.ID] {
+			addProblem(DuplicateID, connection.ID, "connection %d is not unique", connection.ID)
+			continue
+		}
+		seenIDs[connection.ID] = true
+
+		fromLayer, fromOk := nodeLayers[connection.From]
+		toLayer, toOk := nodeLayers[connection.To]
+		if !fromOk {
+			addProblem(MissingNode, connection.ID, "connection %d is from missing node %d", connection.ID, connection.From)
+		}
+		if !toOk {
+			addProblem(MissingNode, connection.ID, "connection %d is to missing node %d", connection.ID, connection.To)
+		}
+		if !fromOk || !toOk {
+			continue
+		}
+
+		// Networks are feed-forward, so any cycle would deadlock activation.
+		if nodeReachable(outgoing, connection.To, connection.From) {
+			addProblem(CyclicConnection, connection.ID, "connection %d from node %d to %d creates a cycle", connection.ID, connection.From, connection.To)
+		} else {
+			outgoing[connection.From] = append(outgoing[connection.From], connection.To)
+		}
+		if fromLayer >= toLayer {
+			addProblem(BackwardConnection, connection.ID, "connection %d goes from layer %d to layer %d", connection.ID, fromLayer, toLayer)
+		}
+	}
+
+	return problems
+}
+
+// RepairGenome fixes the problems in genome that can be fixed without changing its behaviour where it was valid.
+// Duplicate genes are removed, keeping the first occurrence, unknown activation functions are replaced with ones from
+// cfg, and layers are rebuilt from the connections, dropping any that point at missing nodes or create a cycle.
+// Any problems that could not be repaired are returned.
+func RepairGenome(cfg Config, genome Genome) (Genome, []GenomeProblem) {
+	seenIDs := make(map[int]bool)
+	nodes := make([]network.Node, 0, genome.NumNodes())
+	for _, node := range genome.Layers.Nodes() {
+		if seenIDs[node.ID] {
+			continue
+		}
+		seenIDs[node.ID] = true
+		if network.ActivationRegistry.Get(node.ActivationFn) == nil {
+			switch node.Type {
+			case network.Input:
+				node.ActivationFn = cfg.InputActivationFn
+			case network.Output:
+				node.ActivationFn = cfg.OutputActivationFn
+			case network.Bias:
+				node.ActivationFn = network.NoActivation
+			default:
+				node.ActivationFn = network.RandomActivationFunction(cfg.HiddenActivationFns...)
+			}
+		}
+		nodes = append(nodes, node)
+	}
+
+	connections := make([]network.Connection, 0, genome.NumConnections())
+	for _, connection := range genome.Connections {
+		if seenIDs[connection.ID] {
+			continue
+		}
+		seenIDs[connection.ID] = true
+		connections = append(connections, connection)
+	}
+
+	repaired := buildLayeredGenome(nodes, connections)
+	return repaired, ValidateGenome(cfg, repaired)
+}
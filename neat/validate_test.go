@@ -0,0 +1,67 @@
+package neat_test
+
+import (
+	"github.com/jmwri/neatgo/neat"
+	"github.com/jmwri/neatgo/network"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func problemTypes(problems []neat.GenomeProblem) []neat.GenomeProblemType {
+	types := make([]neat.GenomeProblemType, len(problems))
+	for i, problem := range problems {
+		types[i] = problem.Type
+	}
+	return types
+}
+
+func TestValidateGenome_Generated(t *testing.T) {
+	cfg := neat.DefaultConfig(3, 2, 2)
+	genome, err := neat.GenerateGenome(cfg)
+	assert.NoError(t, err, "unexpected error when generating genome")
+	assert.Empty(t, neat.ValidateGenome(cfg, genome))
+}
+
+func TestValidateGenome_Problems(t *testing.T) {
+	cfg := neat.DefaultConfig(2, 1)
+	layers := [][]network.Node{
+		{
+			{ID: 1, Type: network.Input, ActivationFn: network.NoActivation},
+			{ID: 2, Type: network.Hidden, ActivationFn: "made-up"},
+		},
+		{},
+		{
+			{ID: 3, Type: network.Hidden, ActivationFn: network.Sigmoid},
+			{ID: 4, Type: network.Output, ActivationFn: network.Sigmoid},
+			{ID: 4, Type: network.Output, ActivationFn: network.Sigmoid},
+		},
+	}
+	connections := []network.Connection{
+		{ID: 5, From: 1, To: 3, Enabled: true},
+		{ID: 6, From: 3, To: 2, Enabled: true},
+		{ID: 7, From: 2, To: 3, Enabled: true},
+		{ID: 8, From: 3, To: 9, Enabled: true},
+		{ID: 8, From: 1, To: 4, Enabled: true},
+	}
+	genome := neat.NewGenome(layers, connections)
+
+	assert.ElementsMatch(t, []neat.GenomeProblemType{
+		neat.UnknownActivation,
+		neat.MisplacedNode,
+		neat.EmptyLayer,
+		neat.MisplacedNode,
+		neat.DuplicateID,
+		neat.InputCountMismatch,
+		neat.BackwardConnection,
+		neat.CyclicConnection,
+		neat.MissingNode,
+		neat.DuplicateID,
+	}, problemTypes(neat.ValidateGenome(cfg, genome)))
+
+	repaired, remaining := neat.RepairGenome(cfg, genome)
+	// Only the missing input node can't be repaired.
+	assert.Equal(t, []neat.GenomeProblemType{neat.InputCountMismatch}, problemTypes(remaining))
+	assert.Equal(t, 4, repaired.NumNodes())
+	assert.Equal(t, 2, repaired.NumConnections())
+	assert.Equal(t, 4, repaired.NumLayers())
+}